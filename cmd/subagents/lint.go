@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"subagents-mcp/internal/agents"
+	"subagents-mcp/internal/lint"
+	"subagents-mcp/internal/runner"
+)
+
+// runLint implements `subagents lint`. It returns the process exit code:
+// 0 when no errors were found, 1 when the report has errors, 2 on bad usage.
+func runLint(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var agentsDirs stringList
+	fs.Var(&agentsDirs, "agents-dir", "agents directory to lint; repeatable")
+	var agentPacks stringList
+	fs.Var(&agentPacks, "agent-pack", "agent pack to lint, as passed to the server; repeatable, layered below --agents-dir")
+	runnerFlag := fs.String("runner", "", "preferred runner, as passed to the server")
+	runnerConfigFlag := fs.String("runner-config", "", "path to runner config yaml (optional)")
+	jsonFlag := fs.Bool("json", false, "print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if len(agentsDirs) == 0 && len(agentPacks) == 0 {
+		fmt.Fprintln(stderr, "lint: at least one --agents-dir or --agent-pack is required")
+		return 2
+	}
+	packs := make([]agents.PackSpec, 0, len(agentPacks))
+	for _, value := range agentPacks {
+		spec, err := agents.ParsePackSpec(value)
+		if err != nil {
+			fmt.Fprintf(stderr, "lint: %v\n", err)
+			return 2
+		}
+		packs = append(packs, spec)
+	}
+
+	var cfg runner.Config
+	if *runnerConfigFlag != "" {
+		loaded, err := runner.LoadConfig(*runnerConfigFlag)
+		if err != nil {
+			fmt.Fprintf(stderr, "lint: %v\n", err)
+			return 2
+		}
+		cfg = loaded
+	}
+
+	report, err := lint.Run(context.Background(), lint.Options{Packs: packs, Dirs: agentsDirs, Config: cfg, Runner: *runnerFlag})
+	if err != nil {
+		fmt.Fprintf(stderr, "lint: %v\n", err)
+		return 2
+	}
+	if *jsonFlag {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	} else {
+		err = report.Write(stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "lint: %v\n", err)
+		return 2
+	}
+	if report.Errors() > 0 {
+		return 1
+	}
+	return 0
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 
 	"go.uber.org/zap"
@@ -17,10 +19,37 @@ import (
 	"subagents-mcp/internal/validate"
 )
 
+// stringList collects repeated string flags in the order they were given.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
 func main() {
-	agentsDirFlag := flag.String("agents-dir", "", "absolute path to agents directory containing YAML persona files")
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		os.Exit(runLint(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
+	var agentsDirs stringList
+	flag.Var(&agentsDirs, "agents-dir", "absolute path to agents directory containing YAML persona files; repeat to layer sources (later directories override earlier ones)")
+	var agentPacks stringList
+	flag.Var(&agentPacks, "agent-pack", "read-only agent pack: /abs/pack.tar.gz, /abs/pack.zip or /abs/git-repo[@ref], with optional #sha256=<hex>&dir=<subdir>; repeatable, layered below --agents-dir")
+	var allowedRoots stringList
+	flag.Var(&allowedRoots, "allowed-root", "absolute directory glob delegation working directories must fall under; repeatable (default: any directory)")
 	runnerFlag := flag.String("runner", "", "preferred runner (codex|copilot|gemini); leave blank to auto-select")
 	runnerConfigFlag := flag.String("runner-config", "", "path to runner config yaml (optional)")
+	manageAgents := flag.Bool("manage-agents", false, "enable create_agent, update_agent and delete_agent tools writing to the last --agents-dir")
+	strictAgents := flag.Bool("strict-agents", false, "fail when any agent file is broken instead of skipping it")
+	trustProjectEnv := flag.Bool("trust-project-env", false, "let project agents use file: references, ${VAR} expansion and PATH/LD_* overrides in env")
+	trustProjectPermissions := flag.Bool("trust-project-permissions", false, "let project agents request workspace-write or full permissions beyond the agent they shadow or extend")
+	trustPackEnv := flag.Bool("trust-pack-env", false, "let agent packs use file: references, ${VAR} expansion and PATH/LD_* overrides in env")
+	describePersona := flag.Bool("describe-persona", false, "include persona text in describe_agent results")
 	flag.Parse()
 
 	logger, err := logging.New()
@@ -30,33 +59,91 @@ func main() {
 	}
 	defer logger.Sync() //nolint:errcheck
 
-	agentsDir, err := validate.Dir(*agentsDirFlag)
-	if err != nil {
-		logger.Fatal("invalid agents-dir", zap.Error(err))
+	if len(agentsDirs) == 0 && (len(agentPacks) == 0 || *manageAgents) {
+		logger.Fatal("invalid agents-dir", zap.Error(validate.ErrEmptyPath))
+	}
+	// logger.Fatal exits without running deferred calls, so unpacked packs
+	// are removed explicitly before every exit.
+	var packs []*agents.PackRepository
+	closePacks := func() {
+		for _, pack := range packs {
+			if err := pack.Close(); err != nil {
+				logger.Warn("failed to remove unpacked agent pack", zap.Error(err))
+			}
+		}
+	}
+	fatal := func(msg string, fields ...zap.Field) {
+		closePacks()
+		logger.Fatal(msg, fields...)
 	}
 
-	repo := agents.NewYAMLRepository(agentsDir)
+	sources := make([]agents.Repository, 0, len(agentPacks)+len(agentsDirs))
+	for _, value := range agentPacks {
+		spec, err := agents.ParsePackSpec(value)
+		if err != nil {
+			fatal("invalid agent-pack", zap.Error(err))
+		}
+		pack, err := agents.NewPackRepository(context.Background(), spec, agents.Strict(*strictAgents), agents.TrustEnv(*trustPackEnv))
+		if err != nil {
+			fatal("invalid agent-pack", zap.Error(err))
+		}
+		packs = append(packs, pack)
+		logger.Info("agent pack loaded", zap.String("pack", spec.String()), zap.String("digest", pack.Digest()))
+		sources = append(sources, pack)
+	}
+	for _, dir := range agentsDirs {
+		agentsDir, err := validate.Dir(dir)
+		if err != nil {
+			fatal("invalid agents-dir", zap.String("path", dir), zap.Error(err))
+		}
+		sources = append(sources, agents.NewYAMLRepository(agentsDir, agents.Strict(*strictAgents)))
+	}
+
+	repo := agents.NewCompositeRepository(logger, sources...)
 
 	var runnerConfig runner.Config
 	if *runnerConfigFlag != "" {
 		cfg, err := runner.LoadConfig(*runnerConfigFlag)
 		if err != nil {
-			logger.Fatal("invalid runner config", zap.Error(err))
+			fatal("invalid runner config", zap.Error(err))
 		}
 		runnerConfig = cfg
 	}
 
 	selector, err := runner.NewSelector(logger, runnerConfig, *runnerFlag)
 	if err != nil {
-		logger.Fatal("failed to construct runner", zap.Error(err))
+		fatal("failed to construct runner", zap.Error(err))
 	}
 
-	server := mcp.NewServer(logger, repo, selector)
+	for _, root := range allowedRoots {
+		if !filepath.IsAbs(root) {
+			fatal("invalid allowed-root", zap.String("root", root), zap.Error(validate.ErrRelativePath))
+		}
+	}
+
+	opts := []mcp.Option{
+		mcp.WithAllowedRoots(allowedRoots),
+		mcp.WithPersonaDisclosure(*describePersona),
+		mcp.WithStrictAgents(*strictAgents),
+		mcp.WithTrustedProjectEnv(*trustProjectEnv),
+		mcp.WithTrustedProjectPermissions(*trustProjectPermissions),
+	}
+	if *manageAgents {
+		writable, err := validate.Dir(agentsDirs[len(agentsDirs)-1])
+		if err != nil {
+			fatal("invalid agents-dir", zap.Error(err))
+		}
+		// Agents written there may extend any layer below it.
+		opts = append(opts, mcp.WithAgentStore(agents.NewStore(writable, sources[:len(sources)-1]...)))
+		logger.Info("agent management enabled", zap.String("dir", writable))
+	}
+	server := mcp.NewServer(logger, repo, selector, opts...)
 
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
 	if err := server.Serve(ctx, os.Stdin, os.Stdout); err != nil {
-		logger.Fatal("server stopped", zap.Error(err))
+		fatal("server stopped", zap.Error(err))
 	}
+	closePacks()
 }
@@ -0,0 +1,99 @@
+package agents
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// CompositeRepository layers several agent sources. Sources are consulted in
+// order and later sources override earlier ones when agent names collide.
+type CompositeRepository struct {
+	logger  *zap.Logger
+	sources []Repository
+}
+
+func NewCompositeRepository(logger *zap.Logger, sources ...Repository) *CompositeRepository {
+	return &CompositeRepository{logger: logger, sources: sources}
+}
+
+func (r *CompositeRepository) ListAgents(ctx context.Context) ([]Agent, error) {
+	return r.listAgentsOver(ctx, nil)
+}
+
+// listAgentsOver merges the sources in order. Each source may extend agents
+// of lower and of the sources before it.
+func (r *CompositeRepository) listAgentsOver(ctx context.Context, lower []Agent) ([]Agent, error) {
+	var merged []Agent
+	index := make(map[string]int)
+	for _, source := range r.sources {
+		var (
+			agentsList []Agent
+			err        error
+		)
+		if layered, ok := source.(layeredRepository); ok {
+			agentsList, err = layered.listAgentsOver(ctx, append(append([]Agent(nil), lower...), merged...))
+		} else {
+			agentsList, err = source.ListAgents(ctx)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, agent := range agentsList {
+			pos, ok := index[agent.Name]
+			if !ok {
+				index[agent.Name] = len(merged)
+				merged = append(merged, agent)
+				continue
+			}
+			r.logger.Info("agent definition shadowed",
+				zap.String("agent", agent.Name),
+				zap.String("shadowed_source", merged[pos].Source),
+				zap.String("source", agent.Source),
+			)
+			merged[pos] = agent
+		}
+	}
+	return r.dropConflictingAliases(merged), nil
+}
+
+// dropConflictingAliases removes aliases that name another agent or that an
+// earlier agent in catalog order already claims, logging each one, so the
+// merged catalog never resolves a name ambiguously.
+func (r *CompositeRepository) dropConflictingAliases(list []Agent) []Agent {
+	owners := make(map[string]string, len(list))
+	for _, agent := range list {
+		owners[agent.Name] = agent.Name
+	}
+	for i, agent := range list {
+		var kept []string
+		for _, alias := range agent.Aliases {
+			if owner, ok := owners[alias]; ok && owner != agent.Name {
+				r.logger.Warn("agent alias ignored",
+					zap.String("agent", agent.Name),
+					zap.String("alias", alias),
+					zap.String("owner", owner),
+					zap.String("source", agent.Source),
+				)
+				continue
+			}
+			owners[alias] = agent.Name
+			kept = append(kept, alias)
+		}
+		if len(kept) != len(agent.Aliases) {
+			list[i].Aliases = kept
+		}
+	}
+	return list
+}
+
+// Diagnostics aggregates the diagnostics of every source that reports them.
+func (r *CompositeRepository) Diagnostics() []Diagnostic {
+	var diags []Diagnostic
+	for _, source := range r.sources {
+		if reporter, ok := source.(DiagnosticsReporter); ok {
+			diags = append(diags, reporter.Diagnostics()...)
+		}
+	}
+	return diags
+}
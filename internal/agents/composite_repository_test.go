@@ -0,0 +1,129 @@
+package agents
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestCompositeRepository_ListAgents(t *testing.T) {
+	t.Run("later sources override earlier ones", func(t *testing.T) {
+		orgDir := t.TempDir()
+		projectDir := t.TempDir()
+		write(t, filepath.Join(orgDir, "alpha.yaml"), "persona: org alpha\ndescription: org agent\n")
+		write(t, filepath.Join(orgDir, "beta.yaml"), "persona: org beta\ndescription: org only\n")
+		write(t, filepath.Join(projectDir, "alpha.yaml"), "persona: project alpha\ndescription: project agent\n")
+		write(t, filepath.Join(projectDir, "gamma.yaml"), "persona: project gamma\ndescription: project only\n")
+
+		repo := NewCompositeRepository(zap.NewNop(), NewYAMLRepository(orgDir), NewYAMLRepository(projectDir))
+		agents, err := repo.ListAgents(context.Background())
+		if err != nil {
+			t.Fatalf("ListAgents error: %v", err)
+		}
+		if len(agents) != 3 {
+			t.Fatalf("expected 3 agents, got %d", len(agents))
+		}
+		if agents[0].Name != "alpha" || agents[0].Persona != "project alpha" {
+			t.Fatalf("expected project alpha to override org alpha, got %+v", agents[0])
+		}
+		if agents[0].Source != projectDir {
+			t.Fatalf("expected source %q, got %q", projectDir, agents[0].Source)
+		}
+		if agents[1].Name != "beta" || agents[1].Source != orgDir {
+			t.Fatalf("expected org beta, got %+v", agents[1])
+		}
+		if agents[2].Name != "gamma" || agents[2].Source != projectDir {
+			t.Fatalf("expected project gamma, got %+v", agents[2])
+		}
+	})
+
+	t.Run("drops conflicting aliases", func(t *testing.T) {
+		orgDir := t.TempDir()
+		projectDir := t.TempDir()
+		write(t, filepath.Join(orgDir, "alpha.yaml"), "persona: p\ndescription: d\naliases: [shared]\n")
+		write(t, filepath.Join(projectDir, "beta.yaml"), "persona: p\ndescription: d\naliases: [shared, alpha, b]\n")
+
+		repo := NewCompositeRepository(zap.NewNop(), NewYAMLRepository(orgDir), NewYAMLRepository(projectDir))
+		agents, err := repo.ListAgents(context.Background())
+		if err != nil {
+			t.Fatalf("ListAgents error: %v", err)
+		}
+		if got := Lookup(agents, "shared"); got == nil || got.Name != "alpha" {
+			t.Fatalf("expected alpha to keep alias shared, got %+v", got)
+		}
+		if len(agents[1].Aliases) != 1 || agents[1].Aliases[0] != "b" {
+			t.Fatalf("expected beta to keep only alias b, got %v", agents[1].Aliases)
+		}
+	})
+
+	t.Run("propagates source errors", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, "alpha.yaml"), "persona: \ndescription: missing persona\n")
+
+		repo := NewCompositeRepository(zap.NewNop(), NewYAMLRepository(t.TempDir()), NewYAMLRepository(dir, Strict(true)))
+		if _, err := repo.ListAgents(context.Background()); err == nil {
+			t.Fatal("expected validation error")
+		}
+	})
+
+	t.Run("aggregates diagnostics of tolerant sources", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, "alpha.yaml"), "persona: \ndescription: missing persona\n")
+		write(t, filepath.Join(dir, "beta.yaml"), "persona: b\ndescription: fine\n")
+
+		repo := NewCompositeRepository(zap.NewNop(), NewYAMLRepository(t.TempDir()), NewYAMLRepository(dir))
+		agents, err := repo.ListAgents(context.Background())
+		if err != nil {
+			t.Fatalf("ListAgents error: %v", err)
+		}
+		if len(agents) != 1 || agents[0].Name != "beta" {
+			t.Fatalf("expected only beta, got %+v", agents)
+		}
+		diags := repo.Diagnostics()
+		if len(diags) != 1 || diags[0].File != filepath.Join(dir, "alpha.yaml") || !strings.Contains(diags[0].Message, "persona is required") {
+			t.Fatalf("unexpected diagnostics: %+v", diags)
+		}
+	})
+}
+
+func TestCompositeRepository_ExtendsAcrossLayers(t *testing.T) {
+	orgDir := t.TempDir()
+	teamDir := t.TempDir()
+	write(t, filepath.Join(orgDir, "reviewer.yaml"), "persona: org reviewer\ndescription: org\nmodel: gpt-5\ncontext_files: [style.md]\n")
+	write(t, filepath.Join(teamDir, "reviewer.yaml"), "extends: reviewer\npersona: team rules\npersona_mode: append\n")
+	write(t, filepath.Join(teamDir, "go-reviewer.yaml"), "extends: reviewer\ndescription: go\n")
+	write(t, filepath.Join(teamDir, "docs-reviewer.yaml"), "extends: reviewer\ndescription: docs\ncontext_files: [docs.md]\n")
+	write(t, filepath.Join(teamDir, "orphan.yaml"), "extends: orphan\ndescription: nothing below\n")
+
+	repo := NewCompositeRepository(zap.NewNop(), NewYAMLRepository(orgDir), NewYAMLRepository(teamDir))
+	list, err := repo.ListAgents(context.Background())
+	if err != nil {
+		t.Fatalf("ListAgents error: %v", err)
+	}
+	byName := make(map[string]Agent)
+	for _, agent := range list {
+		byName[agent.Name] = agent
+	}
+	if got := byName["reviewer"]; got.Persona != "org reviewer\n\nteam rules" || got.Model != "gpt-5" || got.Source != teamDir {
+		t.Fatalf("expected team reviewer to extend the org definition, got %+v", got)
+	}
+	if got := byName["go-reviewer"]; got.Persona != "org reviewer\n\nteam rules" || got.Description != "go" {
+		t.Fatalf("expected go-reviewer to extend the team reviewer, got %+v", got)
+	}
+	if got := byName["go-reviewer"]; got.Dir != orgDir {
+		t.Fatalf("expected inherited context_files to resolve in %q, got %q", orgDir, got.Dir)
+	}
+	if got := byName["docs-reviewer"]; got.Dir != teamDir {
+		t.Fatalf("expected own context_files to resolve in %q, got %q", teamDir, got.Dir)
+	}
+	if _, ok := byName["orphan"]; ok {
+		t.Fatal("orphan should be skipped")
+	}
+	diags := repo.Diagnostics()
+	if len(diags) != 1 || !strings.Contains(diags[0].Message, "no earlier agents directory defines") {
+		t.Fatalf("expected diagnostic for orphan, got %+v", diags)
+	}
+}
@@ -0,0 +1,30 @@
+package agents
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// checkUntrustedEnv rejects env entries an untrusted agent file could use to
+// read server secrets or hijack the runner process: file: references, ${VAR}
+// expansion and dynamic loader or PATH overrides.
+func checkUntrustedEnv(agent Agent) error {
+	keys := make([]string, 0, len(agent.Env))
+	for key := range agent.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := agent.Env[key]
+		switch {
+		case strings.HasPrefix(value, "file:"):
+			return fmt.Errorf("env %s: file: references are not allowed in untrusted agents", key)
+		case strings.Contains(value, "${"):
+			return fmt.Errorf("env %s: ${VAR} expansion is not allowed in untrusted agents", key)
+		case key == "PATH", strings.HasPrefix(key, "LD_"), strings.HasPrefix(key, "DYLD_"):
+			return fmt.Errorf("env %s may not be set by untrusted agents", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package agents
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// Fingerprint hashes the resolved agent definition, including inherited
+// fields but not where it was loaded from, so any edit that changes how the
+// agent behaves changes the hash. Only env keys are hashed: values may be
+// secrets, which a published hash must not let callers guess.
+func Fingerprint(a Agent) string {
+	keys := make([]string, 0, len(a.Env))
+	for key := range a.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	a.Source = ""
+	a.DefinitionHash = ""
+	payload, err := json.Marshal(struct {
+		Agent
+		EnvKeys []string `json:"env_keys,omitempty"`
+	}{a, keys})
+	if err != nil {
+		return ""
+	}
+	return ContentHash(payload)
+}
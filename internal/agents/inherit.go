@@ -0,0 +1,170 @@
+package agents
+
+import (
+	"fmt"
+	"strings"
+)
+
+// resolveInheritance merges every agent with the chain of agents it extends.
+// Fields set on the child win; the persona is composed according to
+// PersonaMode (replace by default). Bases missing from list are looked up in
+// lower, the already resolved agents of earlier catalog layers; an agent that
+// extends its own name extends the lower layer's definition. Cycles and
+// unknown bases are errors.
+func resolveInheritance(list, lower []Agent) ([]Agent, error) {
+	resolved, errs := resolveEach(list, lower)
+	for _, agent := range list {
+		if err, ok := errs[agent.Name]; ok {
+			return nil, err
+		}
+	}
+	return resolved, nil
+}
+
+// resolveEach is like resolveInheritance but keeps going past broken agents,
+// returning the ones that resolved and an error per agent name that did not.
+func resolveEach(list, lower []Agent) ([]Agent, map[string]error) {
+	listByName, lowerByName := byName(list), byName(lower)
+
+	resolved := make(map[string]Agent, len(list))
+	var resolve func(name string, chain []string) (Agent, error)
+	resolve = func(name string, chain []string) (Agent, error) {
+		if agent, ok := resolved[name]; ok {
+			return agent, nil
+		}
+		for _, seen := range chain {
+			if seen == name {
+				return Agent{}, fmt.Errorf("extends cycle: %s", strings.Join(append(chain, name), " -> "))
+			}
+		}
+		agent, ok := listByName[name]
+		if !ok {
+			if base, ok := lowerByName[name]; ok {
+				return base, nil
+			}
+			return Agent{}, fmt.Errorf("agent %q extends unknown agent %q", chain[len(chain)-1], name)
+		}
+		switch {
+		case agent.Extends == "":
+		case agent.Extends == name:
+			base, ok := lowerByName[name]
+			if !ok {
+				return Agent{}, fmt.Errorf("agent %q extends itself but no earlier agents directory defines %q", name, name)
+			}
+			agent = mergeAgent(base, agent)
+		default:
+			base, err := resolve(agent.Extends, append(chain, name))
+			if err != nil {
+				return Agent{}, err
+			}
+			agent = mergeAgent(base, agent)
+		}
+		resolved[name] = agent
+		return agent, nil
+	}
+
+	out := make([]Agent, 0, len(list))
+	errs := make(map[string]error)
+	for _, agent := range list {
+		merged, err := resolve(agent.Name, nil)
+		if err != nil {
+			errs[agent.Name] = err
+			continue
+		}
+		out = append(out, merged)
+	}
+	return out, errs
+}
+
+// mergeAgent overlays child onto base. Aliases, Deprecated and Version
+// describe the agent's identity and are never inherited.
+func mergeAgent(base, child Agent) Agent {
+	merged := child
+	if merged.Description == "" {
+		merged.Description = base.Description
+	}
+	if merged.Model == "" && merged.Models == nil {
+		merged.Model = base.Model
+		merged.Models = base.Models
+	}
+	if merged.Tags == nil {
+		merged.Tags = base.Tags
+	}
+	if merged.Category == "" {
+		merged.Category = base.Category
+	}
+	if merged.Inputs == nil {
+		merged.Inputs = base.Inputs
+	}
+	if merged.OutputSchema == nil {
+		merged.OutputSchema = base.OutputSchema
+	}
+	if merged.Runners == nil {
+		merged.Runners = base.Runners
+	}
+	if merged.Permissions == "" {
+		merged.Permissions = base.Permissions
+	}
+	if merged.AllowedTools == nil {
+		merged.AllowedTools = base.AllowedTools
+	}
+	if merged.DeniedTools == nil {
+		merged.DeniedTools = base.DeniedTools
+	}
+	if merged.ContextFiles == nil && base.ContextFiles != nil {
+		// Inherited globs resolve where they were declared.
+		merged.ContextFiles = base.ContextFiles
+		merged.Dir = base.Dir
+	}
+	if base.Env != nil {
+		env := make(map[string]string, len(base.Env)+len(child.Env))
+		for k, v := range base.Env {
+			env[k] = v
+		}
+		for k, v := range child.Env {
+			env[k] = v
+		}
+		merged.Env = env
+	}
+	if merged.Requires == nil {
+		merged.Requires = base.Requires
+	}
+	if merged.AllowedRoots == nil {
+		merged.AllowedRoots = base.AllowedRoots
+	}
+	if merged.Timeout == 0 {
+		merged.Timeout = base.Timeout
+	}
+	if merged.PromptTemplate == "" {
+		merged.PromptTemplate = base.PromptTemplate
+	}
+	if merged.Examples == nil {
+		merged.Examples = base.Examples
+	}
+	if merged.MaxExamples == 0 {
+		merged.MaxExamples = base.MaxExamples
+	}
+	if merged.OutputAttempts == 0 {
+		merged.OutputAttempts = base.OutputAttempts
+	}
+	merged.Persona = composePersona(base.Persona, child.Persona, child.PersonaMode)
+	merged.PersonaTemplate = child.PersonaTemplate || base.PersonaTemplate
+	return merged
+}
+
+func composePersona(base, own, mode string) string {
+	switch {
+	case own == "":
+		return base
+	case base == "":
+		return own
+	}
+	switch mode {
+	case PersonaAppend:
+		return base + "\n\n" + own
+	case PersonaPrepend:
+		return own + "\n\n" + base
+	default:
+		return own
+	}
+}
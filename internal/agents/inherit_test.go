@@ -0,0 +1,84 @@
+package agents
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestYAMLRepository_Extends(t *testing.T) {
+	t.Run("merges fields and composes persona", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, "base.yaml"), "persona: base persona\ndescription: base agent\nmodel: gpt-4o\n")
+		write(t, filepath.Join(dir, "appended.yaml"), "extends: base\npersona: extra\npersona_mode: append\n")
+		write(t, filepath.Join(dir, "prepended.yaml"), "extends: appended\npersona: first\npersona_mode: prepend\nmodel: claude\n")
+		write(t, filepath.Join(dir, "replaced.yaml"), "extends: base\npersona: own\ndescription: replaced agent\n")
+
+		agents, err := NewYAMLRepository(dir).ListAgents(context.Background())
+		if err != nil {
+			t.Fatalf("ListAgents error: %v", err)
+		}
+		byName := make(map[string]Agent)
+		for _, a := range agents {
+			byName[a.Name] = a
+		}
+
+		appended := byName["appended"]
+		if appended.Persona != "base persona\n\nextra" || appended.Description != "base agent" || appended.Model != "gpt-4o" {
+			t.Fatalf("unexpected appended agent: %+v", appended)
+		}
+		prepended := byName["prepended"]
+		if prepended.Persona != "first\n\nbase persona\n\nextra" || prepended.Model != "claude" {
+			t.Fatalf("unexpected prepended agent: %+v", prepended)
+		}
+		replaced := byName["replaced"]
+		if replaced.Persona != "own" || replaced.Description != "replaced agent" || replaced.Model != "gpt-4o" {
+			t.Fatalf("unexpected replaced agent: %+v", replaced)
+		}
+	})
+
+	t.Run("validates the resolved agent", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, "base.yaml"), "persona: base persona\ndescription: base agent\n")
+		write(t, filepath.Join(dir, "child.yaml"), "extends: base\nmodel: gpt-4o\n")
+
+		agents, err := NewYAMLRepository(dir).ListAgents(context.Background())
+		if err != nil {
+			t.Fatalf("ListAgents error: %v", err)
+		}
+		if agents[1].Persona != "base persona" {
+			t.Fatalf("expected inherited persona, got %q", agents[1].Persona)
+		}
+	})
+
+	t.Run("detects cycles", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, "a.yaml"), "extends: b\npersona: a\ndescription: a\n")
+		write(t, filepath.Join(dir, "b.yaml"), "extends: a\npersona: b\ndescription: b\n")
+
+		_, err := NewYAMLRepository(dir, Strict(true)).ListAgents(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "cycle") {
+			t.Fatalf("expected cycle error, got %v", err)
+		}
+	})
+
+	t.Run("errors on unknown base", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, "a.yaml"), "extends: missing\npersona: a\ndescription: a\n")
+
+		if _, err := NewYAMLRepository(dir, Strict(true)).ListAgents(context.Background()); err == nil {
+			t.Fatal("expected unknown base error")
+		}
+	})
+
+	t.Run("rejects invalid persona mode", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, "base.yaml"), "persona: base\ndescription: base\n")
+		write(t, filepath.Join(dir, "a.yaml"), "extends: base\npersona: a\npersona_mode: merge\n")
+
+		if _, err := NewYAMLRepository(dir, Strict(true)).ListAgents(context.Background()); err == nil {
+			t.Fatal("expected persona_mode validation error")
+		}
+	})
+}
@@ -0,0 +1,208 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// knownFields lists the keys accepted in an agent file.
+var knownFields = func() []string {
+	t := reflect.TypeOf(agentFile{})
+	fields := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		fields = append(fields, strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0])
+	}
+	return fields
+}()
+
+var yamlLinePattern = regexp.MustCompile(`line (\d+): `)
+
+// LintDir checks every agent file in dir without stopping at the first
+// problem. It returns the agents that loaded cleanly together with every
+// diagnostic found: strict-decoding errors with positions, inheritance and
+// validation failures, names that differ only in case and empty personas.
+// lower holds the agents of earlier directories that files in dir may extend.
+func LintDir(ctx context.Context, dir string, lower ...Agent) ([]Agent, []Diagnostic) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, []Diagnostic{{File: dir, Severity: SeverityError, Message: fmt.Sprintf("read agents dir: %v", err)}}
+	}
+
+	var (
+		parsed []Agent
+		diags  []Diagnostic
+	)
+	files := make(map[string]string)
+	folded := make(map[string]string)
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return nil, append(diags, Diagnostic{File: dir, Severity: SeverityError, Message: ctx.Err().Error()})
+		}
+		if entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		switch filepath.Ext(entry.Name()) {
+		case ".yaml":
+		case ".yml":
+			diags = append(diags, Diagnostic{File: path, Severity: SeverityWarning, Message: "ignored: agent files must use the .yaml extension"})
+			continue
+		default:
+			continue
+		}
+
+		name := strings.TrimSuffix(entry.Name(), ".yaml")
+		if other, ok := folded[strings.ToLower(name)]; ok {
+			diags = append(diags, Diagnostic{File: path, Severity: SeverityWarning, Message: fmt.Sprintf("agent %q differs from %q only in case", name, other)})
+		}
+		folded[strings.ToLower(name)] = name
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			diags = append(diags, Diagnostic{File: path, Severity: SeverityError, Message: err.Error()})
+			continue
+		}
+		fileDiags := lintFile(path, content)
+		diags = append(diags, fileDiags...)
+		if hasErrors(fileDiags) {
+			continue
+		}
+		agent, err := parseAgentFile(name, dir, content)
+		if err != nil {
+			diags = append(diags, Diagnostic{File: path, Severity: SeverityError, Message: err.Error()})
+			continue
+		}
+		parsed = append(parsed, agent)
+		files[name] = path
+	}
+
+	valid := resolveTolerant(parsed, lower, func(name string) string { return files[name] }, &diags)
+	return valid, diags
+}
+
+// lintFile strictly decodes a single agent file.
+func lintFile(path string, content []byte) []Diagnostic {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return yamlDiagnostics(path, err)
+	}
+	if len(doc.Content) == 0 {
+		return []Diagnostic{{File: path, Severity: SeverityError, Message: "file is empty"}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return []Diagnostic{{File: path, Line: root.Line, Column: root.Column, Severity: SeverityError, Message: "agent definition must be a mapping"}}
+	}
+
+	var diags []Diagnostic
+	seen := make(map[string]int)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		at := func(severity, format string, args ...any) {
+			diags = append(diags, Diagnostic{File: path, Line: key.Line, Column: key.Column, Severity: severity, Message: fmt.Sprintf(format, args...)})
+		}
+		if line, ok := seen[key.Value]; ok {
+			at(SeverityError, "duplicate key %q (first defined on line %d)", key.Value, line)
+			continue
+		}
+		seen[key.Value] = key.Line
+		if !isKnownField(key.Value) {
+			if suggestion := closestField(key.Value); suggestion != "" {
+				at(SeverityError, "unknown field %q (did you mean %q?)", key.Value, suggestion)
+			} else {
+				at(SeverityError, "unknown field %q", key.Value)
+			}
+			continue
+		}
+		if key.Value == "persona" && value.Kind == yaml.ScalarNode && strings.TrimSpace(value.Value) == "" {
+			at(SeverityWarning, "persona is empty")
+		}
+	}
+	if hasErrors(diags) {
+		return diags
+	}
+
+	var raw agentFile
+	if err := root.Decode(&raw); err != nil {
+		diags = append(diags, yamlDiagnostics(path, err)...)
+	}
+	return diags
+}
+
+// yamlDiagnostics converts yaml.v3 errors, which embed line numbers in their
+// messages, into diagnostics.
+func yamlDiagnostics(path string, err error) []Diagnostic {
+	messages := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+	diags := make([]Diagnostic, 0, len(messages))
+	for _, msg := range messages {
+		d := Diagnostic{File: path, Severity: SeverityError, Message: strings.TrimPrefix(msg, "yaml: ")}
+		if m := yamlLinePattern.FindStringSubmatchIndex(d.Message); m != nil {
+			d.Line, _ = strconv.Atoi(d.Message[m[2]:m[3]])
+			d.Message = d.Message[:m[0]] + d.Message[m[1]:]
+		}
+		diags = append(diags, d)
+	}
+	return diags
+}
+
+func isKnownField(key string) bool {
+	for _, field := range knownFields {
+		if field == key {
+			return true
+		}
+	}
+	return false
+}
+
+// closestField suggests a known field within two edits of key.
+func closestField(key string) string {
+	best, bestDistance := "", 3
+	for _, field := range knownFields {
+		if d := editDistance(key, field); d < bestDistance {
+			best, bestDistance = field, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func hasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
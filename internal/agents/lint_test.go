@@ -0,0 +1,53 @@
+package agents
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLintDir(t *testing.T) {
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "good.yaml"), "persona: good\ndescription: fine\n")
+	write(t, filepath.Join(dir, "typo.yaml"), "persona: p\ndescripton: d\nmodle: gpt-5\n")
+	write(t, filepath.Join(dir, "dup.yaml"), "persona: p\ndescription: d\npersona: q\n")
+	write(t, filepath.Join(dir, "Good.yaml"), "persona: \"\"\ndescription: d\nextends: good\n")
+	write(t, filepath.Join(dir, "badtype.yaml"), "persona: p\ndescription: d\ntimeout: soon\n")
+	write(t, filepath.Join(dir, "broken.yaml"), "persona: [unclosed\n")
+	write(t, filepath.Join(dir, "orphan.yaml"), "persona: p\ndescription: d\nextends: missing\n")
+	write(t, filepath.Join(dir, "invalid.yaml"), "persona: p\n")
+	write(t, filepath.Join(dir, "other.yml"), "persona: p\n")
+
+	loaded, diags := LintDir(context.Background(), dir)
+
+	var got []string
+	for _, d := range diags {
+		got = append(got, d.String())
+	}
+	report := strings.Join(got, "\n")
+	for _, want := range []string{
+		"typo.yaml:2:1: error: unknown field \"descripton\" (did you mean \"description\"?)",
+		"typo.yaml:3:1: error: unknown field \"modle\" (did you mean \"model\"?)",
+		"dup.yaml:3:1: error: duplicate key \"persona\" (first defined on line 1)",
+		"Good.yaml:1:1: warning: persona is empty",
+		"differs from \"Good\" only in case",
+		"badtype.yaml:3: error: cannot unmarshal",
+		"broken.yaml:",
+		"orphan.yaml: error: agent \"orphan\" extends unknown agent \"missing\"",
+		"invalid.yaml: error: description is required",
+		"other.yml: warning: ignored",
+	} {
+		if !strings.Contains(report, want) {
+			t.Fatalf("expected %q in report:\n%s", want, report)
+		}
+	}
+
+	names := map[string]bool{}
+	for _, agent := range loaded {
+		names[agent.Name] = true
+	}
+	if len(loaded) != 2 || !names["good"] || !names["Good"] {
+		t.Fatalf("expected only good agents to load, got %v", names)
+	}
+}
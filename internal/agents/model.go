@@ -1,6 +1,15 @@
 package agents
 
-import "fmt"
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"subagents-mcp/internal/schema"
+)
 
 // Agent represents a delegateable persona.
 type Agent struct {
@@ -8,8 +17,125 @@ type Agent struct {
 	Persona     string `json:"persona" yaml:"persona"`
 	Description string `json:"description" yaml:"description"`
 	Model       string `json:"model" yaml:"model"`
+	// Models lists fallback models tried in order after Model.
+	Models []string `json:"models,omitempty" yaml:"models,omitempty"`
+	// Tags and Category help callers find agents in large catalogs.
+	Tags     []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Category string   `json:"category,omitempty" yaml:"category,omitempty"`
+	// Aliases are alternative names callers may delegate to.
+	Aliases []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	// Deprecated marks an agent as retired; delegations are forwarded to the
+	// replacement when one is named.
+	Deprecated *Deprecation `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+	// Requires lists conditions that must hold for the agent to be offered.
+	Requires *Requirements `json:"requires,omitempty" yaml:"requires,omitempty"`
+	// Extends names another agent whose fields this agent inherits.
+	Extends string `json:"extends,omitempty" yaml:"extends,omitempty"`
+	// PersonaMode controls how Persona combines with the inherited persona.
+	PersonaMode string `json:"persona_mode,omitempty" yaml:"persona_mode,omitempty"`
+	// PersonaTemplate renders Persona as a text/template at delegation time;
+	// otherwise the persona is used verbatim.
+	PersonaTemplate bool `json:"persona_template,omitempty" yaml:"persona_template,omitempty"`
+	// Inputs is a JSON Schema describing the arguments callers must supply.
+	Inputs map[string]any `json:"inputs,omitempty" yaml:"inputs,omitempty"`
+	// OutputSchema is a JSON Schema the agent's final answer must satisfy.
+	OutputSchema map[string]any `json:"output_schema,omitempty" yaml:"output_schema,omitempty"`
+	// OutputAttempts caps how many times a task is run to obtain output
+	// matching OutputSchema; zero means DefaultOutputAttempts.
+	OutputAttempts int `json:"output_attempts,omitempty" yaml:"output_attempts,omitempty"`
+	// PromptTemplate is a text/template that lays out the runner prompt; empty
+	// uses the runner config default and then the built-in layout.
+	PromptTemplate string `json:"prompt_template,omitempty" yaml:"prompt_template,omitempty"`
+	// Examples are worked input/output pairs shown to the runner before the
+	// task. MaxExamples caps how many are included; zero falls back to the
+	// runner config and then DefaultMaxExamples, and NoExamples leaves them out.
+	Examples    []Example `json:"examples,omitempty" yaml:"examples,omitempty"`
+	MaxExamples int       `json:"max_examples,omitempty" yaml:"max_examples,omitempty"`
+	// Runners, when set, is the ordered list of runners to try for this agent
+	// instead of the global preference and priorities.
+	Runners []string `json:"runners,omitempty" yaml:"runners,omitempty"`
+	// ContextFiles are globs whose contents are included in the prompt. They
+	// resolve against Dir, or against the working directory when prefixed
+	// with "workdir:".
+	ContextFiles []string `json:"context_files,omitempty" yaml:"context_files,omitempty"`
+	// Env sets environment variables for the runner process. Values may use
+	// ${VAR} expansion or "file:<path>" references and are never serialized.
+	Env map[string]string `json:"-" yaml:"env,omitempty"`
+	// AllowedRoots are globs a delegation working directory must fall under.
+	AllowedRoots []string `json:"allowed_roots,omitempty" yaml:"allowed_roots,omitempty"`
+	// Permissions is the runner-neutral sandbox profile for this agent.
+	Permissions string `json:"permissions,omitempty" yaml:"permissions,omitempty"`
+	// AllowedTools and DeniedTools restrict the tools a runner may use, in
+	// Copilot-style syntax such as "shell(git:*)" or "write".
+	AllowedTools []string `json:"allowed_tools,omitempty" yaml:"allowed_tools,omitempty"`
+	DeniedTools  []string `json:"denied_tools,omitempty" yaml:"denied_tools,omitempty"`
+	// Timeout bounds each runner attempt for this agent; zero falls back to
+	// the runner config default.
+	Timeout time.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// Version is an optional author-assigned version label.
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+	// Source identifies where the agent definition was loaded from.
+	Source string `json:"source,omitempty" yaml:"-"`
+	// Dir is the directory context_files resolve against: the one holding
+	// the definition that declared them, which may be a base agent's. It
+	// differs from Source for agent packs.
+	Dir string `json:"-" yaml:"-"`
+	// DefinitionHash fingerprints the resolved definition; see Fingerprint.
+	DefinitionHash string `json:"definition_hash,omitempty" yaml:"-"`
+}
+
+// Deprecation describes a retired agent.
+type Deprecation struct {
+	ReplacedBy string `json:"replaced_by,omitempty" yaml:"replaced_by,omitempty"`
+	Message    string `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+// Example is a task and the ideal response for it.
+type Example struct {
+	Input  string `json:"input" yaml:"input"`
+	Output string `json:"output" yaml:"output"`
+}
+
+// Requirements are availability conditions for an agent. Runners needs at
+// least one of the listed runners installed and configured, Models at least
+// one of the listed models routable; every Env variable must be set and
+// every Files glob must match inside the working directory.
+type Requirements struct {
+	Runners []string `json:"runners,omitempty" yaml:"runners,omitempty"`
+	Models  []string `json:"models,omitempty" yaml:"models,omitempty"`
+	Env     []string `json:"env,omitempty" yaml:"env,omitempty"`
+	Files   []string `json:"files,omitempty" yaml:"files,omitempty"`
 }
 
+// RunnerNames lists the runners requires.runners may name.
+var RunnerNames = []string{"codex", "copilot", "gemini"}
+
+// Persona composition modes for agents that extend another agent.
+const (
+	PersonaReplace = "replace"
+	PersonaAppend  = "append"
+	PersonaPrepend = "prepend"
+)
+
+// Permission profiles understood by every runner. An empty profile keeps
+// each runner's historical defaults.
+const (
+	PermissionsReadOnly       = "read-only"
+	PermissionsWorkspaceWrite = "workspace-write"
+	PermissionsFull           = "full"
+)
+
+// DefaultOutputAttempts is used when an agent declares an output schema but
+// no explicit attempt limit.
+const DefaultOutputAttempts = 3
+
+// DefaultMaxExamples caps the examples included in a prompt when neither the
+// agent nor the runner config sets a limit.
+const DefaultMaxExamples = 3
+
+// NoExamples as max_examples leaves every example out of the prompt.
+const NoExamples = -1
+
 // Validate ensures required fields are present.
 func (a Agent) Validate() error {
 	if a.Name == "" {
@@ -21,5 +147,98 @@ func (a Agent) Validate() error {
 	if a.Description == "" {
 		return fmt.Errorf("description is required for agent %q", a.Name)
 	}
+	switch a.PersonaMode {
+	case "", PersonaReplace, PersonaAppend, PersonaPrepend:
+	default:
+		return fmt.Errorf("persona_mode %q is invalid for agent %q (want append, prepend or replace)", a.PersonaMode, a.Name)
+	}
+	if a.PersonaTemplate {
+		if _, err := template.New(a.Name).Parse(a.Persona); err != nil {
+			return fmt.Errorf("persona template is invalid for agent %q: %w", a.Name, err)
+		}
+	}
+	if a.Inputs != nil {
+		if err := schema.Check(a.Inputs); err != nil {
+			return fmt.Errorf("inputs schema is invalid for agent %q: %w", a.Name, err)
+		}
+	}
+	if a.OutputSchema != nil {
+		if err := schema.Check(a.OutputSchema); err != nil {
+			return fmt.Errorf("output_schema is invalid for agent %q: %w", a.Name, err)
+		}
+	}
+	switch a.Permissions {
+	case "", PermissionsReadOnly, PermissionsWorkspaceWrite, PermissionsFull:
+	default:
+		return fmt.Errorf("permissions %q is invalid for agent %q (want read-only, workspace-write or full)", a.Permissions, a.Name)
+	}
+	for _, allowed := range a.AllowedTools {
+		for _, denied := range a.DeniedTools {
+			if allowed == denied {
+				return fmt.Errorf("tool %q is both allowed and denied for agent %q", allowed, a.Name)
+			}
+		}
+	}
+	for key := range a.Env {
+		if key == "" || strings.ContainsAny(key, "= ") {
+			return fmt.Errorf("env name %q is invalid for agent %q", key, a.Name)
+		}
+	}
+	for _, root := range a.AllowedRoots {
+		if !filepath.IsAbs(root) {
+			return fmt.Errorf("allowed_roots entry %q must be absolute for agent %q", root, a.Name)
+		}
+		if _, err := filepath.Match(root, root); err != nil {
+			return fmt.Errorf("allowed_roots entry %q is invalid for agent %q: %w", root, a.Name, err)
+		}
+	}
+	for _, alias := range a.Aliases {
+		if alias == a.Name {
+			return fmt.Errorf("alias %q repeats the name of agent %q", alias, a.Name)
+		}
+	}
+	if a.Deprecated != nil && a.Deprecated.ReplacedBy == a.Name {
+		return fmt.Errorf("deprecated agent %q cannot be replaced by itself", a.Name)
+	}
+	if a.Requires != nil {
+		for _, name := range a.Requires.Runners {
+			if !slices.Contains(RunnerNames, name) {
+				return fmt.Errorf("requires.runners entry %q is unknown for agent %q (want one of %s)", name, a.Name, strings.Join(RunnerNames, ", "))
+			}
+		}
+		for _, key := range a.Requires.Env {
+			if strings.ContainsAny(key, "= ") {
+				return fmt.Errorf("requires.env name %q is invalid for agent %q", key, a.Name)
+			}
+		}
+		for _, pattern := range a.Requires.Files {
+			clean := filepath.Clean(pattern)
+			if filepath.IsAbs(pattern) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+				return fmt.Errorf("requires.files entry %q must be relative to the working directory for agent %q", pattern, a.Name)
+			}
+			if _, err := filepath.Match(pattern, pattern); err != nil {
+				return fmt.Errorf("requires.files entry %q is invalid for agent %q: %w", pattern, a.Name, err)
+			}
+		}
+	}
+	if a.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative for agent %q", a.Name)
+	}
+	for i, example := range a.Examples {
+		if strings.TrimSpace(example.Input) == "" || strings.TrimSpace(example.Output) == "" {
+			return fmt.Errorf("examples[%d] needs both input and output for agent %q", i, a.Name)
+		}
+	}
+	if a.PromptTemplate != "" {
+		if err := CheckPromptTemplate(a.PromptTemplate); err != nil {
+			return fmt.Errorf("prompt_template is invalid for agent %q: %w", a.Name, err)
+		}
+	}
+	if a.MaxExamples < NoExamples {
+		return fmt.Errorf("max_examples must be -1 (none) or more for agent %q", a.Name)
+	}
+	if a.OutputAttempts < 0 {
+		return fmt.Errorf("output_attempts must not be negative for agent %q", a.Name)
+	}
 	return nil
 }
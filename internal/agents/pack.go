@@ -0,0 +1,555 @@
+package agents
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Limits applied while unpacking agent packs.
+const (
+	maxPackSize    = 64 << 20
+	maxPackFile    = 1 << 20
+	maxPackTotal   = 16 << 20
+	maxPackEntries = 1000
+)
+
+// PackKind tells how an agent pack is stored.
+type PackKind string
+
+const (
+	PackArchive PackKind = "archive"
+	PackGit     PackKind = "git"
+)
+
+// PackSpec identifies an agent pack: a .tar.gz/.tgz/.zip archive, or a local
+// git repository read at Ref.
+type PackSpec struct {
+	Kind PackKind
+	Path string
+	// Ref is the git revision to read; HEAD when empty.
+	Ref string
+	// Dir is the directory inside the pack holding the agent files.
+	Dir string
+	// SHA256 is the expected hex digest of an archive file.
+	SHA256 string
+}
+
+// ParsePackSpec parses "<path>[@<ref>][#sha256=<hex>&dir=<subdir>]". Paths
+// ending in .tar.gz, .tgz or .zip are archives; anything else is a git
+// repository, optionally followed by @ref.
+func ParsePackSpec(value string) (PackSpec, error) {
+	raw, fragment, _ := strings.Cut(value, "#")
+	var spec PackSpec
+	if fragment != "" {
+		params, err := url.ParseQuery(fragment)
+		if err != nil {
+			return PackSpec{}, fmt.Errorf("agent pack %q: %w", value, err)
+		}
+		for key, values := range params {
+			switch key {
+			case "sha256":
+				spec.SHA256 = strings.ToLower(values[0])
+			case "dir":
+				spec.Dir = values[0]
+			default:
+				return PackSpec{}, fmt.Errorf("agent pack %q: unknown option %q", value, key)
+			}
+		}
+	}
+
+	lower := strings.ToLower(raw)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"), strings.HasSuffix(lower, ".zip"):
+		spec.Kind = PackArchive
+		spec.Path = raw
+	default:
+		spec.Kind = PackGit
+		spec.Path = raw
+		if at := strings.LastIndex(raw, "@"); at > strings.LastIndex(raw, "/") {
+			spec.Path, spec.Ref = raw[:at], raw[at+1:]
+		}
+	}
+	return spec, spec.validate()
+}
+
+func (s *PackSpec) validate() error {
+	if !filepath.IsAbs(s.Path) {
+		return fmt.Errorf("agent pack %q: path must be absolute", s.Path)
+	}
+	s.Path = filepath.Clean(s.Path)
+	if s.Kind == PackGit {
+		if s.Ref == "" {
+			s.Ref = "HEAD"
+		}
+		if strings.HasPrefix(s.Ref, "-") {
+			return fmt.Errorf("agent pack %q: invalid ref %q", s.Path, s.Ref)
+		}
+		if s.SHA256 != "" {
+			return fmt.Errorf("agent pack %q: sha256 applies to archives; pin a commit as the ref instead", s.Path)
+		}
+	}
+	if s.SHA256 != "" {
+		if decoded, err := hex.DecodeString(s.SHA256); err != nil || len(decoded) != sha256.Size {
+			return fmt.Errorf("agent pack %q: sha256 must be 64 hex characters", s.Path)
+		}
+	}
+	if s.Dir != "" {
+		dir, ok := packPath(s.Dir)
+		if !ok {
+			return fmt.Errorf("agent pack %q: dir %q must be relative and stay inside the pack", s.Path, s.Dir)
+		}
+		s.Dir = dir
+	}
+	return nil
+}
+
+// String returns a label for the pack used in logs and diagnostics.
+func (s PackSpec) String() string {
+	label := s.Path
+	if s.Kind == PackGit {
+		label += "@" + s.Ref
+	}
+	if s.Dir != "" {
+		label += "/" + s.Dir
+	}
+	return label
+}
+
+// PackRepository serves agents from a read-only snapshot of an agent pack.
+// The pack is verified and unpacked once, when the repository is created, so
+// later changes to the archive or ref are not picked up until a restart.
+type PackRepository struct {
+	spec   PackSpec
+	digest string
+	tmp    string
+	dir    string
+	yaml   *YAMLRepository
+}
+
+// NewPackRepository verifies and unpacks the pack described by spec. Archive
+// entries must be regular files or directories with paths inside the pack;
+// links, oversized files and duplicate entries are refused. Entries outside
+// spec.Dir are skipped. Agent files are confined to the unpacked copy and,
+// unless opts include TrustEnv(true), their env is untrusted like a project
+// agent's. Call Close to remove the unpacked copy.
+func NewPackRepository(ctx context.Context, spec PackSpec, opts ...YAMLOption) (*PackRepository, error) {
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+	tmp, err := os.MkdirTemp("", "subagents-pack-*")
+	if err != nil {
+		return nil, fmt.Errorf("agent pack %s: %w", spec, err)
+	}
+	r := &PackRepository{spec: spec, tmp: tmp}
+	if err := r.unpack(ctx); err != nil {
+		r.Close() //nolint:errcheck
+		return nil, fmt.Errorf("agent pack %s: %w", spec, err)
+	}
+	r.yaml = NewYAMLRepository(r.dir, append([]YAMLOption{TrustEnv(false)}, opts...)...)
+	r.yaml.root = r.dir
+	return r, nil
+}
+
+// Digest identifies the snapshot: "sha256:<hex>" of an archive file or
+// "git:<commit>" for a repository.
+func (r *PackRepository) Digest() string {
+	return r.digest
+}
+
+func (r *PackRepository) ListAgents(ctx context.Context) ([]Agent, error) {
+	return r.listAgentsOver(ctx, nil)
+}
+
+// listAgentsOver loads the pack's agents over lower, reporting the pack
+// rather than the unpacked copy as their source.
+func (r *PackRepository) listAgentsOver(ctx context.Context, lower []Agent) ([]Agent, error) {
+	list, err := r.yaml.listAgentsOver(ctx, lower)
+	if err != nil {
+		return nil, err
+	}
+	return r.relabelAgents(list), nil
+}
+
+// Lint runs LintDir over the unpacked agents, labelling results like
+// ListAgents and Diagnostics do.
+func (r *PackRepository) Lint(ctx context.Context, lower ...Agent) ([]Agent, []Diagnostic) {
+	list, diags := LintDir(ctx, r.dir, lower...)
+	return r.relabelAgents(list), r.relabel(diags)
+}
+
+// File labels the file of agent name, as diagnostics do.
+func (r *PackRepository) File(name string) string {
+	return r.spec.String() + ":" + name + ".yaml"
+}
+
+// Diagnostics reports skipped agent files by their path inside the pack.
+func (r *PackRepository) Diagnostics() []Diagnostic {
+	return r.relabel(r.yaml.Diagnostics())
+}
+
+func (r *PackRepository) relabelAgents(list []Agent) []Agent {
+	for i := range list {
+		list[i].Source = r.spec.String()
+	}
+	return list
+}
+
+func (r *PackRepository) relabel(diags []Diagnostic) []Diagnostic {
+	for i := range diags {
+		if rel, err := filepath.Rel(r.dir, diags[i].File); err == nil && !strings.HasPrefix(rel, "..") {
+			diags[i].File = r.spec.String() + ":" + filepath.ToSlash(rel)
+		}
+	}
+	return diags
+}
+
+// Close removes the unpacked copy of the pack.
+func (r *PackRepository) Close() error {
+	if err := filepath.WalkDir(r.tmp, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			return os.Chmod(p, 0o755)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return os.RemoveAll(r.tmp)
+}
+
+func (r *PackRepository) unpack(ctx context.Context) error {
+	root := filepath.Join(r.tmp, "pack")
+	if err := os.Mkdir(root, 0o755); err != nil {
+		return err
+	}
+	u := &unpacker{root: root, dir: r.spec.Dir, seen: map[string]bool{}}
+
+	switch r.spec.Kind {
+	case PackArchive:
+		content, err := readLimited(r.spec.Path)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(content)
+		digest := hex.EncodeToString(sum[:])
+		if r.spec.SHA256 != "" && r.spec.SHA256 != digest {
+			return fmt.Errorf("checksum mismatch: want sha256 %s, got %s", r.spec.SHA256, digest)
+		}
+		r.digest = "sha256:" + digest
+		if strings.HasSuffix(strings.ToLower(r.spec.Path), ".zip") {
+			err = u.zip(content)
+		} else {
+			err = u.tarGz(content)
+		}
+		if err != nil {
+			return err
+		}
+	case PackGit:
+		commit, err := git(ctx, r.spec.Path, "rev-parse", "--verify", "--end-of-options", r.spec.Ref+"^{commit}")
+		if err != nil {
+			return err
+		}
+		commit = bytes.TrimSpace(commit)
+		r.digest = "git:" + string(commit)
+		if err := gitArchive(ctx, r.spec.Path, string(commit), r.spec.Dir, u); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown pack kind %q", r.spec.Kind)
+	}
+
+	dir := root
+	if r.spec.Dir != "" {
+		dir = filepath.Join(root, filepath.FromSlash(r.spec.Dir))
+	} else if single := singleTopDir(root); single != "" {
+		// Tarballs commonly wrap everything in one top-level directory.
+		dir = single
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("agents dir %q not found in pack", r.spec.Dir)
+	}
+	r.dir = dir
+	return readOnly(root)
+}
+
+// git runs a read-only git command against a local repository.
+func git(ctx context.Context, repo string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", repo}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// gitArchive streams `git archive` of commit into u. Only dir is exported
+// when set, and the stream may not exceed maxPackSize.
+func gitArchive(ctx context.Context, repo, commit, dir string, u *unpacker) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	args := []string{"-C", repo, "archive", "--format=tar", commit}
+	if dir != "" {
+		args = append(args, "--", ":(literal)"+dir)
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("git archive: %w", err)
+	}
+	archive := &cappedReader{r: stdout, n: maxPackSize}
+	if err := u.tar(archive); err != nil {
+		cancel()
+		cmd.Wait() //nolint:errcheck
+		return err
+	}
+	// Consume the padding after the end-of-archive marker.
+	if _, err := io.Copy(io.Discard, archive); err != nil {
+		cancel()
+		cmd.Wait() //nolint:errcheck
+		return err
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("git archive: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// cappedReader reads at most n bytes from r and fails if r holds more.
+type cappedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if c.n <= 0 {
+		var probe [1]byte
+		if n, err := c.r.Read(probe[:]); n == 0 {
+			return 0, err
+		}
+		return 0, fmt.Errorf("archive exceeds %d bytes", maxPackSize)
+	}
+	if int64(len(p)) > c.n {
+		p = p[:c.n]
+	}
+	n, err := c.r.Read(p)
+	c.n -= int64(n)
+	return n, err
+}
+
+func readLimited(name string) ([]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	content, err := io.ReadAll(io.LimitReader(f, maxPackSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxPackSize {
+		return nil, fmt.Errorf("archive exceeds %d bytes", maxPackSize)
+	}
+	return content, nil
+}
+
+// unpacker writes archive entries below root, enforcing the pack limits.
+// When dir is set, entries outside it are skipped.
+type unpacker struct {
+	root    string
+	dir     string
+	seen    map[string]bool
+	entries int
+	total   int64
+}
+
+func (u *unpacker) tarGz(content []byte) error {
+	gz, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	return u.tar(gz)
+}
+
+func (u *unpacker) tar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if u.outside(header.Name) {
+			continue
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = u.add(header.Name, true, nil, 0)
+		case tar.TypeReg:
+			err = u.add(header.Name, false, tr, header.Size)
+		case tar.TypeXGlobalHeader:
+			// git archive records the commit id here.
+		default:
+			err = fmt.Errorf("entry %q: only regular files and directories are allowed", header.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (u *unpacker) zip(content []byte) error {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		if u.outside(f.Name) {
+			continue
+		}
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			err = u.add(f.Name, true, nil, 0)
+		case mode.IsRegular():
+			var rc io.ReadCloser
+			if rc, err = f.Open(); err == nil {
+				err = u.add(f.Name, false, rc, int64(f.UncompressedSize64))
+				rc.Close()
+			}
+		default:
+			err = fmt.Errorf("entry %q: only regular files and directories are allowed", f.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outside reports whether an entry lies outside the agents dir, so it is not
+// needed. Names escaping the pack are left for add to reject.
+func (u *unpacker) outside(name string) bool {
+	if u.dir == "" {
+		return false
+	}
+	rel, ok := packPath(name)
+	if !ok {
+		return false
+	}
+	return rel != u.dir && !strings.HasPrefix(rel, u.dir+"/") && !strings.HasPrefix(u.dir, rel+"/")
+}
+
+func (u *unpacker) add(name string, isDir bool, r io.Reader, size int64) error {
+	rel, ok := packPath(name)
+	if !ok {
+		return fmt.Errorf("entry %q escapes the pack", name)
+	}
+	if rel == "." {
+		return nil
+	}
+	u.entries++
+	if u.entries > maxPackEntries {
+		return fmt.Errorf("pack has more than %d entries", maxPackEntries)
+	}
+	target := filepath.Join(u.root, filepath.FromSlash(rel))
+	if isDir {
+		return os.MkdirAll(target, 0o755)
+	}
+	if u.seen[rel] {
+		return fmt.Errorf("entry %q appears more than once", name)
+	}
+	u.seen[rel] = true
+	if size > maxPackFile {
+		return fmt.Errorf("entry %q exceeds %d bytes", name, maxPackFile)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	content, err := io.ReadAll(io.LimitReader(r, maxPackFile+1))
+	if err != nil {
+		return fmt.Errorf("entry %q: %w", name, err)
+	}
+	if len(content) > maxPackFile {
+		return fmt.Errorf("entry %q exceeds %d bytes", name, maxPackFile)
+	}
+	u.total += int64(len(content))
+	if u.total > maxPackTotal {
+		return fmt.Errorf("pack contents exceed %d bytes", maxPackTotal)
+	}
+	return os.WriteFile(target, content, 0o644)
+}
+
+// packPath cleans an archive entry name, rejecting absolute paths and paths
+// that climb out of the pack.
+func packPath(name string) (string, bool) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if path.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", false
+	}
+	cleaned := path.Clean(name)
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", false
+	}
+	return cleaned, true
+}
+
+// singleTopDir returns root's only entry when it is a directory holding
+// everything in the pack.
+func singleTopDir(root string) string {
+	entries, err := os.ReadDir(root)
+	if err != nil || len(entries) != 1 || !entries[0].IsDir() {
+		return ""
+	}
+	return filepath.Join(root, entries[0].Name())
+}
+
+// readOnly drops write permission from the unpacked tree.
+func readOnly(root string) error {
+	var dirs []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			dirs = append(dirs, p)
+			return nil
+		}
+		return os.Chmod(p, 0o444)
+	})
+	if err != nil {
+		return err
+	}
+	// Children first, so parents are still writable while we walk.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chmod(dirs[i], 0o555); err != nil {
+			return err
+		}
+	}
+	return nil
+}
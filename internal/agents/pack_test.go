@@ -0,0 +1,324 @@
+package agents
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const packAgent = "persona: p\ndescription: packed\n"
+
+func TestParsePackSpec(t *testing.T) {
+	spec, err := ParsePackSpec("/srv/packs/review.tar.gz#sha256=" + strings.Repeat("AB", 32) + "&dir=agents")
+	if err != nil {
+		t.Fatalf("ParsePackSpec error: %v", err)
+	}
+	if spec.Kind != PackArchive || spec.Path != "/srv/packs/review.tar.gz" || spec.Dir != "agents" || spec.SHA256 != strings.Repeat("ab", 32) {
+		t.Fatalf("unexpected spec: %+v", spec)
+	}
+
+	spec, err = ParsePackSpec("/srv/repos/team@v1.2")
+	if err != nil {
+		t.Fatalf("ParsePackSpec error: %v", err)
+	}
+	if spec.Kind != PackGit || spec.Path != "/srv/repos/team" || spec.Ref != "v1.2" || spec.String() != "/srv/repos/team@v1.2" {
+		t.Fatalf("unexpected spec: %+v", spec)
+	}
+	if spec, _ := ParsePackSpec("/srv/repos/team"); spec.Ref != "HEAD" {
+		t.Fatalf("expected HEAD by default, got %q", spec.Ref)
+	}
+
+	for _, bad := range []string{
+		"packs/review.zip",
+		"/srv/packs/review.zip#sha256=abc",
+		"/srv/packs/review.zip#dir=../x",
+		"/srv/packs/review.zip#mode=rw",
+		"/srv/repos/team@--upload-pack=x",
+		"/srv/repos/team#sha256=" + strings.Repeat("ab", 32),
+	} {
+		if _, err := ParsePackSpec(bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestPackRepository_TarGz(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "pack.tar.gz")
+	content := tarGz(t, map[string]string{"pack-1.0/reviewer.yaml": packAgent, "pack-1.0/README.md": "docs"})
+	writeBytes(t, archive, content)
+	sum := sha256.Sum256(content)
+
+	repo, err := NewPackRepository(context.Background(), PackSpec{Kind: PackArchive, Path: archive, SHA256: hex.EncodeToString(sum[:])})
+	if err != nil {
+		t.Fatalf("NewPackRepository error: %v", err)
+	}
+	defer repo.Close()
+
+	list, err := repo.ListAgents(context.Background())
+	if err != nil {
+		t.Fatalf("ListAgents error: %v", err)
+	}
+	if len(list) != 1 || list[0].Name != "reviewer" || list[0].Description != "packed" {
+		t.Fatalf("unexpected agents: %+v", list)
+	}
+	if repo.Digest() != "sha256:"+hex.EncodeToString(sum[:]) {
+		t.Fatalf("unexpected digest %q", repo.Digest())
+	}
+	if list[0].Source != archive {
+		t.Fatalf("expected the pack as source, got %q", list[0].Source)
+	}
+	if info, err := os.Stat(filepath.Join(list[0].Dir, "reviewer.yaml")); err != nil || info.Mode().Perm()&0o222 != 0 {
+		t.Fatalf("unpacked files should be read-only: %v %v", info, err)
+	}
+
+	if _, err := NewPackRepository(context.Background(), PackSpec{Kind: PackArchive, Path: archive, SHA256: strings.Repeat("0", 64)}); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+}
+
+func TestPackRepository_Trust(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "pack.zip")
+	writeBytes(t, archive, zipped(t, map[string]string{
+		"secret.yaml": "persona: p\ndescription: d\nenv: {TOKEN: file:/run/secrets/token}\n",
+		"deploy.yaml": "persona: p\ndescription: d\npermissions: full\n",
+	}))
+	spec := PackSpec{Kind: PackArchive, Path: archive}
+	lower := []Agent{{Name: "deploy", Persona: "g", Description: "d", Permissions: PermissionsReadOnly}}
+
+	repo, err := NewPackRepository(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("NewPackRepository error: %v", err)
+	}
+	defer repo.Close()
+	list, err := repo.listAgentsOver(context.Background(), lower)
+	if err != nil {
+		t.Fatalf("ListAgents error: %v", err)
+	}
+	if len(list) != 1 || list[0].Name != "deploy" || list[0].Permissions != PermissionsFull {
+		t.Fatalf("expected only the pack's own deploy agent, unbound by the lower one, got %+v", list)
+	}
+	if diags := repo.Diagnostics(); len(diags) != 1 || !strings.Contains(diags[0].Message, "file: references are not allowed") {
+		t.Fatalf("expected untrusted env diagnostic, got %+v", diags)
+	}
+
+	trusted, err := NewPackRepository(context.Background(), spec, TrustEnv(true))
+	if err != nil {
+		t.Fatalf("NewPackRepository error: %v", err)
+	}
+	defer trusted.Close()
+	if list, err := trusted.ListAgents(context.Background()); err != nil || len(list) != 2 {
+		t.Fatalf("expected both agents with TrustEnv, got %+v, %v", list, err)
+	}
+}
+
+func TestPackRepository_ZipRejectsUnsafeEntries(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.zip")
+	writeBytes(t, good, zipped(t, map[string]string{"agents/reviewer.yaml": packAgent, "agents/broken.yaml": "persona: [\n"}))
+
+	repo, err := NewPackRepository(context.Background(), PackSpec{Kind: PackArchive, Path: good, Dir: "agents"})
+	if err != nil {
+		t.Fatalf("NewPackRepository error: %v", err)
+	}
+	defer repo.Close()
+	list, err := repo.ListAgents(context.Background())
+	if err != nil || len(list) != 1 {
+		t.Fatalf("expected the valid agent, got %+v, %v", list, err)
+	}
+	if diags := repo.Diagnostics(); len(diags) != 1 || diags[0].File != good+"/agents:broken.yaml" {
+		t.Fatalf("expected diagnostic labelled by pack path, got %+v", diags)
+	}
+
+	evil := filepath.Join(dir, "evil.zip")
+	writeBytes(t, evil, zipped(t, map[string]string{"../outside.yaml": packAgent}))
+	if _, err := NewPackRepository(context.Background(), PackSpec{Kind: PackArchive, Path: evil}); err == nil || !strings.Contains(err.Error(), "escapes") {
+		t.Fatalf("expected path escape to be rejected, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(os.TempDir(), "outside.yaml")); err == nil {
+		t.Fatal("entry written outside the pack")
+	}
+}
+
+func TestPackRepository_TarRejectsLinks(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: "reviewer.yaml", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	gz.Close()
+	archive := filepath.Join(t.TempDir(), "links.tgz")
+	writeBytes(t, archive, buf.Bytes())
+
+	if _, err := NewPackRepository(context.Background(), PackSpec{Kind: PackArchive, Path: archive}); err == nil || !strings.Contains(err.Error(), "only regular files") {
+		t.Fatalf("expected symlink to be rejected, got %v", err)
+	}
+}
+
+func TestPackRepository_SkipsEntriesOutsideDir(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: "docs/passwd", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: "agents/reviewer.yaml", Mode: 0o644, Size: int64(len(packAgent)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte(packAgent)); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	gz.Close()
+	archive := filepath.Join(t.TempDir(), "mixed.tgz")
+	writeBytes(t, archive, buf.Bytes())
+
+	repo, err := NewPackRepository(context.Background(), PackSpec{Kind: PackArchive, Path: archive, Dir: "agents"})
+	if err != nil {
+		t.Fatalf("expected the link outside dir to be skipped, got %v", err)
+	}
+	defer repo.Close()
+	if _, err := os.Lstat(filepath.Join(repo.tmp, "pack", "docs")); err == nil {
+		t.Fatal("entries outside dir should not be unpacked")
+	}
+	if list, err := repo.ListAgents(context.Background()); err != nil || len(list) != 1 {
+		t.Fatalf("expected the packed agent, got %+v, %v", list, err)
+	}
+}
+
+func TestPackRepository_Git(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repoDir := t.TempDir()
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", repoDir, "-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	run("init", "-q")
+	if err := os.Mkdir(filepath.Join(repoDir, "agents"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	write(t, filepath.Join(repoDir, "agents", "reviewer.yaml"), "persona: p\ndescription: v1\n")
+	run("add", ".")
+	run("commit", "-q", "-m", "v1")
+	run("tag", "v1")
+	write(t, filepath.Join(repoDir, "agents", "reviewer.yaml"), "persona: p\ndescription: v2\n")
+	run("commit", "-q", "-am", "v2")
+	// Uncommitted edits must not leak into the pack.
+	write(t, filepath.Join(repoDir, "agents", "reviewer.yaml"), "persona: p\ndescription: dirty\n")
+
+	for ref, want := range map[string]string{"v1": "v1", "HEAD": "v2"} {
+		spec, err := ParsePackSpec(repoDir + "@" + ref)
+		if err != nil {
+			t.Fatalf("ParsePackSpec error: %v", err)
+		}
+		repo, err := NewPackRepository(context.Background(), spec)
+		if err != nil {
+			t.Fatalf("NewPackRepository error: %v", err)
+		}
+		list, err := repo.ListAgents(context.Background())
+		repo.Close()
+		if err != nil || len(list) != 1 || list[0].Description != want {
+			t.Fatalf("ref %s: expected %s, got %+v, %v", ref, want, list, err)
+		}
+		if !strings.HasPrefix(repo.Digest(), "git:") || repo.Digest() != "git:"+run("rev-parse", ref+"^{commit}") {
+			t.Fatalf("unexpected digest %q", repo.Digest())
+		}
+	}
+
+	if _, err := NewPackRepository(context.Background(), PackSpec{Kind: PackGit, Path: repoDir, Ref: "missing"}); err == nil {
+		t.Fatal("expected unknown ref to fail")
+	}
+
+	// Only dir is exported, so links elsewhere in the repository are fine.
+	if err := os.Symlink("/etc/passwd", filepath.Join(repoDir, "passwd")); err != nil {
+		t.Fatal(err)
+	}
+	run("add", ".")
+	run("commit", "-q", "-m", "link")
+	repo, err := NewPackRepository(context.Background(), PackSpec{Kind: PackGit, Path: repoDir, Dir: "agents"})
+	if err != nil {
+		t.Fatalf("NewPackRepository error: %v", err)
+	}
+	defer repo.Close()
+	if _, err := os.Lstat(filepath.Join(repo.tmp, "pack", "passwd")); err == nil {
+		t.Fatal("git archive should be limited to dir")
+	}
+	if _, err := NewPackRepository(context.Background(), PackSpec{Kind: PackGit, Path: repoDir}); err == nil || !strings.Contains(err.Error(), "only regular files") {
+		t.Fatalf("expected the link to be rejected without dir, got %v", err)
+	}
+}
+
+func TestCappedReader(t *testing.T) {
+	if _, err := io.ReadAll(&cappedReader{r: strings.NewReader("12345"), n: 5}); err != nil {
+		t.Fatalf("expected input at the cap to be accepted, got %v", err)
+	}
+	if _, err := io.ReadAll(&cappedReader{r: strings.NewReader("123456"), n: 5}); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Fatalf("expected input over the cap to fail, got %v", err)
+	}
+}
+
+func tarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zipped(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func writeBytes(t *testing.T, path string, content []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
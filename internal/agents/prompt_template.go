@@ -0,0 +1,95 @@
+package agents
+
+import (
+	"fmt"
+	"sort"
+	"text/template"
+	"text/template/parse"
+)
+
+// promptTemplateFields are the variables a prompt_template may reference.
+var promptTemplateFields = map[string]struct{}{
+	"Persona":            {},
+	"Task":               {},
+	"ContextFiles":       {},
+	"Examples":           {},
+	"OutputInstructions": {},
+	"WorkingDirectory":   {},
+	"Date":               {},
+	"GitBranch":          {},
+	"RepoName":           {},
+}
+
+// CheckPromptTemplate parses a prompt template and verifies it only uses
+// known variables and includes the task.
+func CheckPromptTemplate(text string) error {
+	tmpl, err := template.New("prompt").Parse(text)
+	if err != nil {
+		return err
+	}
+	hasTask := false
+	for _, name := range TemplateFields(tmpl) {
+		if _, ok := promptTemplateFields[name]; !ok {
+			return fmt.Errorf("unknown variable {{.%s}}", name)
+		}
+		if name == "Task" {
+			hasTask = true
+		}
+	}
+	if !hasTask {
+		return fmt.Errorf("template must include {{.Task}}")
+	}
+	return nil
+}
+
+// TemplateFields lists the top-level fields (.Name) a template references.
+func TemplateFields(tmpl *template.Template) []string {
+	seen := make(map[string]struct{})
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.FieldNode:
+			seen[n.Ident[0]] = struct{}{}
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+		case *parse.WithNode:
+			walk(n.Pipe)
+		}
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			walk(t.Tree.Root)
+		}
+	}
+
+	fields := make([]string, 0, len(seen))
+	for name := range seen {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return fields
+}
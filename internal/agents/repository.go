@@ -6,3 +6,16 @@ import "context"
 type Repository interface {
 	ListAgents(ctx context.Context) ([]Agent, error)
 }
+
+// layeredRepository is implemented by repositories whose agents may extend
+// agents of earlier layers in a CompositeRepository.
+type layeredRepository interface {
+	listAgentsOver(ctx context.Context, lower []Agent) ([]Agent, error)
+}
+
+// DiagnosticsReporter is implemented by repositories that skip broken agent
+// definitions instead of failing; Diagnostics describes what was skipped by
+// the latest load.
+type DiagnosticsReporter interface {
+	Diagnostics() []Diagnostic
+}
@@ -0,0 +1,64 @@
+package agents
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Lookup finds the agent called name. Exact names win over aliases; among
+// aliases the first match in catalog order is used.
+func Lookup(list []Agent, name string) *Agent {
+	for i := range list {
+		if list[i].Name == name {
+			return &list[i]
+		}
+	}
+	for i := range list {
+		for _, alias := range list[i].Aliases {
+			if alias == name {
+				return &list[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Resolve looks up name and follows deprecation redirects to the agent that
+// should serve a delegation. The returned warnings describe every alias and
+// deprecation that was applied, in order.
+func Resolve(list []Agent, name string) (*Agent, []string, error) {
+	agent := Lookup(list, name)
+	if agent == nil {
+		return nil, nil, fmt.Errorf("agent %q not found", name)
+	}
+
+	var warnings []string
+	if agent.Name != name {
+		warnings = append(warnings, fmt.Sprintf("%q is an alias of agent %q", name, agent.Name))
+	}
+	seen := []string{agent.Name}
+	for agent.Deprecated != nil {
+		warning := fmt.Sprintf("agent %q is deprecated", agent.Name)
+		if agent.Deprecated.Message != "" {
+			warning += ": " + agent.Deprecated.Message
+		}
+		target := agent.Deprecated.ReplacedBy
+		if target == "" {
+			warnings = append(warnings, warning)
+			break
+		}
+		next := Lookup(list, target)
+		if next == nil {
+			return nil, warnings, fmt.Errorf("agent %q is deprecated and its replacement %q was not found", agent.Name, target)
+		}
+		for _, name := range seen {
+			if name == next.Name {
+				return nil, warnings, fmt.Errorf("deprecation cycle: %s -> %s", strings.Join(seen, " -> "), next.Name)
+			}
+		}
+		warnings = append(warnings, fmt.Sprintf("%s; forwarded to %q", warning, next.Name))
+		seen = append(seen, next.Name)
+		agent = next
+	}
+	return agent, warnings, nil
+}
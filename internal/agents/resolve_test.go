@@ -0,0 +1,52 @@
+package agents
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	catalog := []Agent{
+		{Name: "reviewer", Aliases: []string{"code-reviewer", "old-reviewer"}},
+		{Name: "old-reviewer", Deprecated: &Deprecation{ReplacedBy: "code-reviewer", Message: "renamed"}},
+		{Name: "legacy", Deprecated: &Deprecation{Message: "no longer maintained"}},
+		{Name: "gone", Deprecated: &Deprecation{ReplacedBy: "missing"}},
+		{Name: "loop-a", Deprecated: &Deprecation{ReplacedBy: "loop-b"}},
+		{Name: "loop-b", Deprecated: &Deprecation{ReplacedBy: "loop-a"}},
+	}
+
+	cases := []struct {
+		name     string
+		want     string
+		warnings []string
+		err      string
+	}{
+		{name: "reviewer", want: "reviewer"},
+		{name: "code-reviewer", want: "reviewer", warnings: []string{`"code-reviewer" is an alias of agent "reviewer"`}},
+		{name: "old-reviewer", want: "reviewer", warnings: []string{`agent "old-reviewer" is deprecated: renamed; forwarded to "reviewer"`}},
+		{name: "legacy", want: "legacy", warnings: []string{`agent "legacy" is deprecated: no longer maintained`}},
+		{name: "gone", err: `replacement "missing" was not found`},
+		{name: "loop-a", err: "deprecation cycle: loop-a -> loop-b -> loop-a"},
+		{name: "nobody", err: `agent "nobody" not found`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			agent, warnings, err := Resolve(catalog, tc.name)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if agent.Name != tc.want {
+				t.Fatalf("expected %s, got %s", tc.want, agent.Name)
+			}
+			if strings.Join(warnings, "|") != strings.Join(tc.warnings, "|") {
+				t.Fatalf("expected warnings %q, got %q", tc.warnings, warnings)
+			}
+		})
+	}
+}
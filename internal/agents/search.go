@@ -0,0 +1,114 @@
+package agents
+
+import (
+	"sort"
+	"strings"
+)
+
+// Query narrows and ranks an agent catalog.
+type Query struct {
+	// Text is matched case-insensitively against name, aliases, description
+	// and tags.
+	// Every whitespace-separated term must match.
+	Text string
+	// Tags an agent must all carry.
+	Tags []string
+	// Category the agent must belong to.
+	Category string
+}
+
+// Search returns the agents matching q. When q.Text is set, results are ranked
+// by relevance (name matches first, then tags, then description); otherwise
+// catalog order is kept.
+func Search(list []Agent, q Query) []Agent {
+	terms := strings.Fields(strings.ToLower(q.Text))
+
+	type scored struct {
+		agent Agent
+		score int
+	}
+	var matches []scored
+	for _, agent := range list {
+		if q.Category != "" && !strings.EqualFold(agent.Category, q.Category) {
+			continue
+		}
+		if !hasAllTags(agent, q.Tags) {
+			continue
+		}
+		score, ok := scoreAgent(agent, terms)
+		if !ok {
+			continue
+		}
+		matches = append(matches, scored{agent: agent, score: score})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+	out := make([]Agent, 0, len(matches))
+	for _, m := range matches {
+		out = append(out, m.agent)
+	}
+	return out
+}
+
+func hasAllTags(agent Agent, tags []string) bool {
+	for _, want := range tags {
+		found := false
+		for _, tag := range agent.Tags {
+			if strings.EqualFold(tag, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// scoreAgent sums per-term relevance; ok is false when any term is absent.
+func scoreAgent(agent Agent, terms []string) (int, bool) {
+	name := strings.ToLower(agent.Name)
+	description := strings.ToLower(agent.Description)
+
+	total := 0
+	for _, term := range terms {
+		score := 0
+		switch {
+		case name == term:
+			score = 100
+		case strings.HasPrefix(name, term):
+			score = 60
+		case strings.Contains(name, term):
+			score = 40
+		}
+		for _, alias := range agent.Aliases {
+			alias = strings.ToLower(alias)
+			switch {
+			case alias == term && score < 90:
+				score = 90
+			case strings.Contains(alias, term) && score < 40:
+				score = 40
+			}
+		}
+		for _, tag := range agent.Tags {
+			tag = strings.ToLower(tag)
+			switch {
+			case tag == term && score < 50:
+				score = 50
+			case strings.Contains(tag, term) && score < 30:
+				score = 30
+			}
+		}
+		if score == 0 && strings.Contains(description, term) {
+			score = 10
+		}
+		if score == 0 {
+			return 0, false
+		}
+		total += score
+	}
+	return total, true
+}
@@ -0,0 +1,45 @@
+package agents
+
+import "testing"
+
+func TestSearch(t *testing.T) {
+	catalog := []Agent{
+		{Name: "changelog-writer", Description: "Writes release notes", Tags: []string{"docs", "release"}, Category: "writing"},
+		{Name: "docs-fetcher", Description: "Docs excerpt fetcher", Tags: []string{"research"}, Category: "research"},
+		{Name: "reviewer", Description: "Reviews Go code and docs", Tags: []string{"go", "review"}, Category: "quality"},
+	}
+
+	names := func(list []Agent) []string {
+		out := make([]string, 0, len(list))
+		for _, a := range list {
+			out = append(out, a.Name)
+		}
+		return out
+	}
+
+	cases := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"no filters keeps order", Query{}, []string{"changelog-writer", "docs-fetcher", "reviewer"}},
+		{"ranks name over tag over description", Query{Text: "DOCS"}, []string{"docs-fetcher", "changelog-writer", "reviewer"}},
+		{"all terms must match", Query{Text: "docs go"}, []string{"reviewer"}},
+		{"tags filter", Query{Tags: []string{"Release", "docs"}}, []string{"changelog-writer"}},
+		{"category filter", Query{Category: "Research"}, []string{"docs-fetcher"}},
+		{"no match", Query{Text: "kubernetes"}, []string{}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := names(Search(catalog, tc.query))
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("expected %v, got %v", tc.want, got)
+				}
+			}
+		})
+	}
+}
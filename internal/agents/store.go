@@ -0,0 +1,227 @@
+package agents
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrAgentExists is returned when creating an agent whose file already exists.
+	ErrAgentExists = errors.New("agent already exists")
+	// ErrAgentNotFound is returned when updating or deleting a missing agent file.
+	ErrAgentNotFound = errors.New("agent not found")
+	// ErrHashMismatch is returned when the caller's expected hash is stale.
+	ErrHashMismatch = errors.New("agent changed since it was read")
+)
+
+var agentNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// ContentHash returns the hash used for optimistic concurrency on agent files.
+func ContentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Store writes agent definitions into a single agents directory. Writes are
+// atomic (temp file plus rename) and the directory must still load cleanly
+// after every change.
+type Store struct {
+	dir string
+	// lower are the catalog layers below dir, which its agents may extend.
+	lower []Repository
+	mu    sync.Mutex
+}
+
+func NewStore(dir string, lower ...Repository) *Store {
+	return &Store{dir: dir, lower: lower}
+}
+
+// Dir returns the directory the store writes to.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Create writes a new agent file and returns its content hash.
+func (s *Store) Create(ctx context.Context, name string, content []byte) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path, err := s.path(name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("%w: %q", ErrAgentExists, name)
+	}
+	if err := s.check(ctx, name, content); err != nil {
+		return "", err
+	}
+	if err := writeAtomic(path, content); err != nil {
+		return "", err
+	}
+	return ContentHash(content), nil
+}
+
+// Update replaces an agent file when its current hash equals expectedHash.
+func (s *Store) Update(ctx context.Context, name string, content []byte, expectedHash string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path, err := s.current(name, expectedHash)
+	if err != nil {
+		return "", err
+	}
+	if err := s.check(ctx, name, content); err != nil {
+		return "", err
+	}
+	if err := writeAtomic(path, content); err != nil {
+		return "", err
+	}
+	return ContentHash(content), nil
+}
+
+// Delete removes an agent file when its current hash equals expectedHash.
+// Deleting an agent that others extend is refused.
+func (s *Store) Delete(ctx context.Context, name string, expectedHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path, err := s.current(name, expectedHash)
+	if err != nil {
+		return err
+	}
+	if err := s.check(ctx, name, nil); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("delete %s.yaml: %w", name, err)
+	}
+	return nil
+}
+
+// Hash returns the current hash of name's file, the value update and delete
+// expect.
+func (s *Store) Hash(name string) (string, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return "", err
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: %q in %s", ErrAgentNotFound, name, s.dir)
+	}
+	if err != nil {
+		return "", fmt.Errorf("read %s.yaml: %w", name, err)
+	}
+	return ContentHash(content), nil
+}
+
+func (s *Store) path(name string) (string, error) {
+	if !agentNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid agent name %q: use letters, digits, '-' and '_'", name)
+	}
+	return filepath.Join(s.dir, name+".yaml"), nil
+}
+
+// current returns the path of an existing agent file after checking its hash.
+func (s *Store) current(name, expectedHash string) (string, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return "", err
+	}
+	if expectedHash == "" {
+		return "", fmt.Errorf("expected_hash is required")
+	}
+	hash, err := s.Hash(name)
+	if err != nil {
+		return "", err
+	}
+	if hash != expectedHash {
+		return "", fmt.Errorf("%w: %q is now %s", ErrHashMismatch, name, hash)
+	}
+	return path, nil
+}
+
+// check loads the directory as it would look with name replaced by content
+// (or removed when content is nil) and validates every agent.
+func (s *Store) check(ctx context.Context, name string, content []byte) error {
+	existing, err := NewYAMLRepository(s.dir, Strict(true)).parseAll(ctx, nil)
+	if err != nil {
+		return err
+	}
+	list := make([]Agent, 0, len(existing)+1)
+	for _, agent := range existing {
+		if agent.Name != name {
+			list = append(list, agent)
+		}
+	}
+	if content != nil {
+		if err := strictErrors(lintFile(name+".yaml", content)); err != nil {
+			return err
+		}
+		agent, err := parseAgentFile(name, s.dir, content)
+		if err != nil {
+			return fmt.Errorf("parse %s.yaml: %w", name, err)
+		}
+		list = append(list, agent)
+	}
+	var base []Agent
+	if len(s.lower) > 0 {
+		if base, err = NewCompositeRepository(zap.NewNop(), s.lower...).ListAgents(ctx); err != nil {
+			return err
+		}
+	}
+	_, err = resolveAndValidate(list, base)
+	return err
+}
+
+// strictErrors joins the error diagnostics of a strict decode, if any.
+func strictErrors(diags []Diagnostic) error {
+	var problems []string
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			problems = append(problems, d.String())
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(problems, "; "))
+}
+
+func writeAtomic(path string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
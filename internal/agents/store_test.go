@@ -0,0 +1,89 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStoreLifecycle(t *testing.T) {
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "base.yaml"), "persona: base\ndescription: base\n")
+	store := NewStore(dir)
+	ctx := context.Background()
+
+	hash, err := store.Create(ctx, "child", []byte("extends: base\ndescription: child\n"))
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	if _, err := store.Create(ctx, "child", []byte("persona: p\ndescription: d\n")); !errors.Is(err, ErrAgentExists) {
+		t.Fatalf("expected ErrAgentExists, got %v", err)
+	}
+
+	if _, err := store.Update(ctx, "child", []byte("persona: p\ndescription: d\n"), "sha256:stale"); !errors.Is(err, ErrHashMismatch) {
+		t.Fatalf("expected ErrHashMismatch, got %v", err)
+	}
+	newHash, err := store.Update(ctx, "child", []byte("extends: base\ndescription: updated\n"), hash)
+	if err != nil {
+		t.Fatalf("Update error: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "child.yaml"))
+	if err != nil || ContentHash(content) != newHash {
+		t.Fatalf("expected file to match returned hash: %v", err)
+	}
+
+	baseHash := ContentHash([]byte("persona: base\ndescription: base\n"))
+	if err := store.Delete(ctx, "base", baseHash); err == nil || !strings.Contains(err.Error(), "unknown agent") {
+		t.Fatalf("expected delete of extended agent to fail, got %v", err)
+	}
+	if err := store.Delete(ctx, "child", newHash); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if err := store.Delete(ctx, "child", newHash); !errors.Is(err, ErrAgentNotFound) {
+		t.Fatalf("expected ErrAgentNotFound, got %v", err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("expected only base.yaml to remain, got %v", entries)
+	}
+}
+
+func TestStoreRejectsInvalidDefinitions(t *testing.T) {
+	store := NewStore(t.TempDir())
+	ctx := context.Background()
+
+	cases := map[string]struct {
+		name    string
+		content string
+	}{
+		"bad name":      {name: "../escape", content: "persona: p\ndescription: d\n"},
+		"unknown field": {name: "a", content: "persona: p\ndescription: d\npersonality: x\n"},
+		"invalid agent": {name: "a", content: "persona: p\n"},
+	}
+	for label, tc := range cases {
+		if _, err := store.Create(ctx, tc.name, []byte(tc.content)); err == nil {
+			t.Fatalf("%s: expected error", label)
+		}
+	}
+	entries, _ := os.ReadDir(store.Dir())
+	if len(entries) != 0 {
+		t.Fatalf("expected no files after rejected writes, got %v", entries)
+	}
+}
+
+func TestStoreExtendsLowerLayers(t *testing.T) {
+	orgDir := t.TempDir()
+	write(t, filepath.Join(orgDir, "reviewer.yaml"), "persona: org\ndescription: org reviewer\n")
+	ctx := context.Background()
+
+	if _, err := NewStore(t.TempDir()).Create(ctx, "strict", []byte("extends: reviewer\n")); err == nil {
+		t.Fatal("expected unknown base without lower layers")
+	}
+	if _, err := NewStore(t.TempDir(), NewYAMLRepository(orgDir)).Create(ctx, "strict", []byte("extends: reviewer\n")); err != nil {
+		t.Fatalf("expected base from lower layer to resolve: %v", err)
+	}
+}
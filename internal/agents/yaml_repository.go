@@ -2,24 +2,177 @@ package agents
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"subagents-mcp/internal/validate"
 )
 
-// YAMLRepository loads agents from YAML files in a directory.
+// ProjectAgentsDir is where project-local agents live, relative to a
+// delegation working directory.
+const ProjectAgentsDir = ".subagents/agents"
+
+// YAMLRepository loads agents from YAML files in a directory. By default
+// files that fail to parse, resolve or validate are skipped and reported
+// through Diagnostics; strict repositories fail the whole load instead.
 type YAMLRepository struct {
 	baseDir string
+	// root, when set, confines agent files to this directory tree.
+	root   string
+	strict bool
+	// bindLower keeps agents within the allowed_roots and permissions of the
+	// lower agents they shadow or extend.
+	bindLower bool
+	// trustEnv allows env values that read server state: file: references
+	// and ${VAR} expansion.
+	trustEnv bool
+	// trustPermissions allows permission profiles above read-only, or above
+	// the profile of the lower agent an agent shadows or extends.
+	trustPermissions bool
+
+	mu          sync.Mutex
+	diagnostics []Diagnostic
+}
+
+// YAMLOption configures a YAMLRepository.
+type YAMLOption func(*YAMLRepository)
+
+// TrustEnv controls whether agent env values may use file: references and
+// ${VAR} expansion and set loader variables such as PATH or LD_PRELOAD.
+// Repositories trust their files by default; project repositories and agent
+// packs do not.
+func TrustEnv(enabled bool) YAMLOption {
+	return func(r *YAMLRepository) {
+		r.trustEnv = enabled
+	}
+}
+
+// TrustPermissions controls whether agents may request any permission
+// profile. Untrusted agents are capped at read-only, or at the profile of the
+// lower agent they shadow or extend. Repositories trust their files by
+// default; project repositories do not.
+func TrustPermissions(enabled bool) YAMLOption {
+	return func(r *YAMLRepository) {
+		r.trustPermissions = enabled
+	}
+}
+
+// Strict makes ListAgents fail on the first broken agent file.
+func Strict(enabled bool) YAMLOption {
+	return func(r *YAMLRepository) {
+		r.strict = enabled
+	}
+}
+
+func NewYAMLRepository(baseDir string, opts ...YAMLOption) *YAMLRepository {
+	r := &YAMLRepository{baseDir: baseDir, trustEnv: true, trustPermissions: true}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
-func NewYAMLRepository(baseDir string) *YAMLRepository {
-	return &YAMLRepository{baseDir: baseDir}
+// NewProjectRepository returns a repository for agents versioned inside the
+// working directory under ProjectAgentsDir. It returns nil when the project
+// does not define any agents. Symlinks that escape workdir are refused, and
+// env values and permissions are untrusted unless opts include TrustEnv(true)
+// or TrustPermissions(true).
+func NewProjectRepository(workdir string, opts ...YAMLOption) (*YAMLRepository, error) {
+	root, err := validate.Dir(workdir)
+	if err != nil {
+		return nil, err
+	}
+	candidate := filepath.Join(root, filepath.FromSlash(ProjectAgentsDir))
+	if _, err := os.Lstat(candidate); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	dir, err := validate.Dir(candidate)
+	if err != nil {
+		return nil, fmt.Errorf("project agents dir: %w", err)
+	}
+	if _, err := validate.Within(root, dir); err != nil {
+		return nil, fmt.Errorf("project agents dir: %w", err)
+	}
+	r := NewYAMLRepository(dir, append([]YAMLOption{TrustEnv(false), TrustPermissions(false)}, opts...)...)
+	r.root = root
+	r.bindLower = true
+	return r, nil
 }
 
 func (r *YAMLRepository) ListAgents(ctx context.Context) ([]Agent, error) {
+	return r.listAgentsOver(ctx, nil)
+}
+
+// listAgentsOver loads the directory with lower as the agents of earlier
+// layers that its files may extend. Project agents that shadow or extend a
+// lower agent are bound by its allowed_roots and permissions.
+func (r *YAMLRepository) listAgentsOver(ctx context.Context, lower []Agent) ([]Agent, error) {
+	if r.strict {
+		agentsList, err := r.parseAll(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		resolved, err := resolveAndValidate(agentsList, lower)
+		if err != nil || !r.untrusted() {
+			return resolved, err
+		}
+		parsed, lowerByName := byName(agentsList), byName(lower)
+		for i, agent := range resolved {
+			if resolved[i], err = r.bindToLower(agent, parsed, lowerByName); err != nil {
+				return nil, fmt.Errorf("validate %s.yaml: %w", agent.Name, err)
+			}
+		}
+		return resolved, nil
+	}
+
+	var diags []Diagnostic
+	agentsList, err := r.parseAll(ctx, &diags)
+	if err != nil {
+		return nil, err
+	}
+	valid := resolveTolerant(agentsList, lower, r.file, &diags)
+	if r.untrusted() {
+		parsed, lowerByName := byName(agentsList), byName(lower)
+		bound := valid[:0]
+		for _, agent := range valid {
+			agent, err := r.bindToLower(agent, parsed, lowerByName)
+			if err != nil {
+				diags = append(diags, Diagnostic{File: r.file(agent.Name), Severity: SeverityError, Message: err.Error()})
+				continue
+			}
+			bound = append(bound, agent)
+		}
+		valid = bound
+	}
+	r.mu.Lock()
+	r.diagnostics = diags
+	r.mu.Unlock()
+	return valid, nil
+}
+
+// Diagnostics returns the problems found by the latest ListAgents call.
+func (r *YAMLRepository) Diagnostics() []Diagnostic {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Diagnostic(nil), r.diagnostics...)
+}
+
+func (r *YAMLRepository) file(name string) string {
+	return filepath.Join(r.baseDir, name+".yaml")
+}
+
+// parseAll decodes every agent file in the directory without resolving
+// inheritance or validating. When diags is nil the first unreadable or
+// malformed file is an error; otherwise it is recorded and skipped.
+func (r *YAMLRepository) parseAll(ctx context.Context, diags *[]Diagnostic) ([]Agent, error) {
 	entries, err := os.ReadDir(r.baseDir)
 	if err != nil {
 		return nil, fmt.Errorf("read agents dir: %w", err)
@@ -42,31 +195,302 @@ func (r *YAMLRepository) ListAgents(ctx context.Context) ([]Agent, error) {
 		}
 
 		name := strings.TrimSuffix(entry.Name(), ".yaml")
-		content, err := os.ReadFile(filepath.Join(r.baseDir, entry.Name()))
+		path := filepath.Join(r.baseDir, entry.Name())
+		agent, err := r.parseFile(name, path)
 		if err != nil {
-			return nil, fmt.Errorf("read %s: %w", entry.Name(), err)
+			if diags == nil {
+				return nil, err
+			}
+			*diags = append(*diags, Diagnostic{File: path, Severity: SeverityError, Message: err.Error()})
+			continue
 		}
+		agentsList = append(agentsList, agent)
+	}
+	return agentsList, nil
+}
 
-		var raw struct {
-			Persona     string `yaml:"persona"`
-			Description string `yaml:"description"`
-			Model       string `yaml:"model"`
+func (r *YAMLRepository) parseFile(name, path string) (Agent, error) {
+	if r.root != "" {
+		if _, err := validate.Within(r.root, path); err != nil {
+			return Agent{}, fmt.Errorf("read %s: %w", filepath.Base(path), err)
 		}
-		if err := yaml.Unmarshal(content, &raw); err != nil {
-			return nil, fmt.Errorf("parse %s: %w", entry.Name(), err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Agent{}, fmt.Errorf("read %s: %w", filepath.Base(path), err)
+	}
+	agent, err := parseAgentFile(name, r.baseDir, content)
+	if err != nil {
+		return Agent{}, fmt.Errorf("parse %s: %w", filepath.Base(path), err)
+	}
+	if !r.trustEnv {
+		if err := checkUntrustedEnv(agent); err != nil {
+			return Agent{}, fmt.Errorf("parse %s: %w", filepath.Base(path), err)
 		}
+	}
+	return agent, nil
+}
 
-		agent := Agent{
-			Name:        name,
-			Persona:     strings.TrimSpace(raw.Persona),
-			Description: strings.TrimSpace(raw.Description),
-			Model:       strings.TrimSpace(raw.Model),
+// resolveTolerant applies inheritance and validation, returning the agents
+// that pass and recording a diagnostic against fileOf(name) for the rest.
+func resolveTolerant(agentsList, lower []Agent, fileOf func(string) string, diags *[]Diagnostic) []Agent {
+	resolved, errs := resolveEach(agentsList, lower)
+	for _, agent := range agentsList {
+		if err, ok := errs[agent.Name]; ok {
+			*diags = append(*diags, Diagnostic{File: fileOf(agent.Name), Severity: SeverityError, Message: err.Error()})
 		}
+	}
+	valid := make([]Agent, 0, len(resolved))
+	for _, agent := range resolved {
 		if err := agent.Validate(); err != nil {
-			return nil, fmt.Errorf("validate %s: %w", entry.Name(), err)
+			*diags = append(*diags, Diagnostic{File: fileOf(agent.Name), Severity: SeverityError, Message: err.Error()})
+			continue
 		}
-		agentsList = append(agentsList, agent)
+		agent.DefinitionHash = Fingerprint(agent)
+		valid = append(valid, agent)
 	}
+	return valid
+}
 
-	return agentsList, nil
+// untrusted reports whether agents need checking against the lower agents
+// they build on.
+func (r *YAMLRepository) untrusted() bool {
+	return r.bindLower || !r.trustEnv || !r.trustPermissions
+}
+
+// bindToLower checks a resolved agent against the lower agents it shadows or
+// extends, given the parsed agents of this directory and the lower agents by
+// name. With bindLower their allowed_roots and permissions apply and setting
+// different ones is an error; without trustPermissions the agent's profile
+// defaults to and may not exceed theirs, or read-only; without trustEnv the agent keeps only
+// the env declared in this directory.
+func (r *YAMLRepository) bindToLower(agent Agent, parsed, lower map[string]Agent) (Agent, error) {
+	anchors, env, inherits := lowerAnchors(agent.Name, parsed, lower)
+	if r.bindLower {
+		for _, anchor := range anchors {
+			var err error
+			if agent, err = bindTo(agent, anchor); err != nil {
+				return agent, err
+			}
+		}
+	}
+	if !r.trustPermissions {
+		limit := PermissionsReadOnly
+		for _, anchor := range anchors {
+			if anchor.Permissions != "" {
+				limit = anchor.Permissions
+			}
+		}
+		switch {
+		case agent.Permissions == "":
+			// Runner defaults for an unset profile can be permissive.
+			agent.Permissions = limit
+		case permissionRank(agent.Permissions) > permissionRank(limit):
+			return agent, fmt.Errorf("agent %q may not request permissions %q (untrusted agents are limited to %q)", agent.Name, agent.Permissions, limit)
+		}
+	}
+	if !r.trustEnv && inherits {
+		agent.Env = env
+	}
+	agent.DefinitionHash = Fingerprint(agent)
+	return agent, nil
+}
+
+// lowerAnchors returns the lower agents that name shadows or that its extends
+// chain reaches, the env declared along the chain within parsed, and whether
+// the chain inherits from a lower agent.
+func lowerAnchors(name string, parsed, lower map[string]Agent) (anchors []Agent, env map[string]string, inherits bool) {
+	if shadowed, ok := lower[name]; ok {
+		anchors = append(anchors, shadowed)
+	}
+	var chain []Agent
+	seen := make(map[string]bool)
+	for current := name; !seen[current]; {
+		seen[current] = true
+		agent, ok := parsed[current]
+		if !ok {
+			if base, ok := lower[current]; ok {
+				anchors = append(anchors, base)
+				inherits = true
+			}
+			break
+		}
+		chain = append(chain, agent)
+		if agent.Extends == current {
+			if base, ok := lower[current]; ok {
+				anchors = append(anchors, base)
+				inherits = true
+			}
+			break
+		}
+		if agent.Extends == "" {
+			break
+		}
+		current = agent.Extends
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		for k, v := range chain[i].Env {
+			if env == nil {
+				env = make(map[string]string)
+			}
+			env[k] = v
+		}
+	}
+	return anchors, env, inherits
+}
+
+// bindTo applies the allowed_roots and permissions of anchor so an untrusted
+// agent cannot lift them. Setting a different value is an error.
+func bindTo(agent, anchor Agent) (Agent, error) {
+	if anchor.AllowedRoots != nil {
+		switch {
+		case agent.AllowedRoots == nil:
+			agent.AllowedRoots = anchor.AllowedRoots
+		case !slices.Equal(agent.AllowedRoots, anchor.AllowedRoots):
+			return agent, fmt.Errorf("agent %q may not override allowed_roots %q of %q from %s", agent.Name, anchor.AllowedRoots, anchor.Name, anchor.Source)
+		}
+	}
+	if anchor.Permissions != "" {
+		switch agent.Permissions {
+		case "":
+			agent.Permissions = anchor.Permissions
+		case anchor.Permissions:
+		default:
+			return agent, fmt.Errorf("agent %q may not override permissions %q of %q from %s", agent.Name, anchor.Permissions, anchor.Name, anchor.Source)
+		}
+	}
+	return agent, nil
+}
+
+// permissionRank orders permission profiles by how much they allow.
+func permissionRank(profile string) int {
+	switch profile {
+	case PermissionsWorkspaceWrite:
+		return 1
+	case PermissionsFull:
+		return 2
+	default:
+		return 0
+	}
+}
+
+func byName(list []Agent) map[string]Agent {
+	m := make(map[string]Agent, len(list))
+	for _, agent := range list {
+		m[agent.Name] = agent
+	}
+	return m
+}
+
+// resolveAndValidate applies inheritance and validates the resulting agents.
+func resolveAndValidate(agentsList, lower []Agent) ([]Agent, error) {
+	resolved, err := resolveInheritance(agentsList, lower)
+	if err != nil {
+		return nil, err
+	}
+	for i, agent := range resolved {
+		if err := agent.Validate(); err != nil {
+			return nil, fmt.Errorf("validate %s.yaml: %w", agent.Name, err)
+		}
+		resolved[i].DefinitionHash = Fingerprint(agent)
+	}
+
+	return resolved, nil
+}
+
+// agentFile mirrors the on-disk YAML schema of an agent definition.
+type agentFile struct {
+	Persona         string            `yaml:"persona"`
+	Description     string            `yaml:"description"`
+	Model           string            `yaml:"model"`
+	Models          []string          `yaml:"models"`
+	Extends         string            `yaml:"extends"`
+	PersonaMode     string            `yaml:"persona_mode"`
+	PersonaTemplate bool              `yaml:"persona_template"`
+	Inputs          map[string]any    `yaml:"inputs"`
+	OutputSchema    map[string]any    `yaml:"output_schema"`
+	OutputAttempts  int               `yaml:"output_attempts"`
+	Runners         []string          `yaml:"runners"`
+	Timeout         time.Duration     `yaml:"timeout"`
+	Permissions     string            `yaml:"permissions"`
+	AllowedTools    []string          `yaml:"allowed_tools"`
+	DeniedTools     []string          `yaml:"denied_tools"`
+	ContextFiles    []string          `yaml:"context_files"`
+	Env             map[string]string `yaml:"env"`
+	AllowedRoots    []string          `yaml:"allowed_roots"`
+	Tags            []string          `yaml:"tags"`
+	Category        string            `yaml:"category"`
+	Aliases         []string          `yaml:"aliases"`
+	Deprecated      *Deprecation      `yaml:"deprecated"`
+	Requires        *Requirements     `yaml:"requires"`
+	PromptTemplate  string            `yaml:"prompt_template"`
+	Examples        []Example         `yaml:"examples"`
+	Version         string            `yaml:"version"`
+	MaxExamples     int               `yaml:"max_examples"`
+}
+
+// parseAgentFile decodes a single agent definition without resolving
+// inheritance or validating it.
+func parseAgentFile(name, source string, content []byte) (Agent, error) {
+	var raw agentFile
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return Agent{}, err
+	}
+	return Agent{
+		Name:            name,
+		Persona:         strings.TrimSpace(raw.Persona),
+		Description:     strings.TrimSpace(raw.Description),
+		Model:           strings.TrimSpace(raw.Model),
+		Models:          trimList(raw.Models),
+		Extends:         strings.TrimSpace(raw.Extends),
+		PersonaMode:     strings.TrimSpace(raw.PersonaMode),
+		PersonaTemplate: raw.PersonaTemplate,
+		Inputs:          raw.Inputs,
+		OutputSchema:    raw.OutputSchema,
+		OutputAttempts:  raw.OutputAttempts,
+		Runners:         trimList(raw.Runners),
+		Timeout:         raw.Timeout,
+		Permissions:     strings.TrimSpace(raw.Permissions),
+		AllowedTools:    trimList(raw.AllowedTools),
+		DeniedTools:     trimList(raw.DeniedTools),
+		ContextFiles:    trimList(raw.ContextFiles),
+		Env:             raw.Env,
+		AllowedRoots:    trimList(raw.AllowedRoots),
+		Tags:            trimList(raw.Tags),
+		Category:        strings.TrimSpace(raw.Category),
+		Aliases:         trimList(raw.Aliases),
+		Deprecated:      trimDeprecation(raw.Deprecated),
+		Requires:        trimRequirements(raw.Requires),
+		PromptTemplate:  strings.TrimSpace(raw.PromptTemplate),
+		Examples:        raw.Examples,
+		Version:         strings.TrimSpace(raw.Version),
+		MaxExamples:     raw.MaxExamples,
+		Source:          source,
+		Dir:             source,
+	}, nil
+}
+
+func trimDeprecation(d *Deprecation) *Deprecation {
+	if d == nil {
+		return nil
+	}
+	return &Deprecation{ReplacedBy: strings.TrimSpace(d.ReplacedBy), Message: strings.TrimSpace(d.Message)}
+}
+
+func trimRequirements(r *Requirements) *Requirements {
+	if r == nil {
+		return nil
+	}
+	return &Requirements{Runners: trimList(r.Runners), Models: trimList(r.Models), Env: trimList(r.Env), Files: trimList(r.Files)}
+}
+
+// trimList trims every entry and drops empty ones.
+func trimList(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if trimmed := strings.TrimSpace(v); trimmed != "" {
+			out = append(out, trimmed)
+		}
+	}
+	return out
 }
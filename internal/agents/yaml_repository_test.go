@@ -4,7 +4,9 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestYAMLRepository_ListAgents(t *testing.T) {
@@ -33,7 +35,7 @@ func TestYAMLRepository_ListAgents(t *testing.T) {
 		dir := t.TempDir()
 		write(t, filepath.Join(dir, "alpha.yaml"), "persona: \ndescription: missing persona\n")
 
-		repo := NewYAMLRepository(dir)
+		repo := NewYAMLRepository(dir, Strict(true))
 		if _, err := repo.ListAgents(context.Background()); err == nil {
 			t.Fatal("expected validation error")
 		}
@@ -60,9 +62,394 @@ func TestYAMLRepository_ListAgents(t *testing.T) {
 	})
 }
 
+func TestYAMLRepository_Inputs(t *testing.T) {
+	t.Run("loads inputs schema", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, "review.yaml"), `persona: reviewer
+description: code review
+inputs:
+  type: object
+  properties:
+    base_ref: {type: string}
+    paths:
+      type: array
+      items: {type: string}
+  required: [base_ref, paths]
+`)
+
+		agents, err := NewYAMLRepository(dir).ListAgents(context.Background())
+		if err != nil {
+			t.Fatalf("ListAgents error: %v", err)
+		}
+		props, ok := agents[0].Inputs["properties"].(map[string]any)
+		if !ok || props["paths"] == nil {
+			t.Fatalf("unexpected inputs schema: %#v", agents[0].Inputs)
+		}
+	})
+
+	t.Run("rejects malformed inputs schema", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, "review.yaml"), "persona: reviewer\ndescription: code review\ninputs:\n  type: text\n")
+
+		if _, err := NewYAMLRepository(dir, Strict(true)).ListAgents(context.Background()); err == nil {
+			t.Fatal("expected schema validation error")
+		}
+	})
+
+	t.Run("rejects prompt templates with unknown variables", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, "review.yaml"), "persona: reviewer\ndescription: code review\nprompt_template: \"{{.Persona}} {{.Nope}} {{.Task}}\"\n")
+
+		_, err := NewYAMLRepository(dir, Strict(true)).ListAgents(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "unknown variable {{.Nope}}") {
+			t.Fatalf("expected unknown variable error, got %v", err)
+		}
+	})
+
+	t.Run("rejects unknown required runners", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, "review.yaml"), "persona: reviewer\ndescription: code review\nrequires:\n  runners: [codx]\n")
+
+		_, err := NewYAMLRepository(dir, Strict(true)).ListAgents(context.Background())
+		if err == nil || !strings.Contains(err.Error(), `requires.runners entry "codx" is unknown`) {
+			t.Fatalf("expected unknown runner error, got %v", err)
+		}
+	})
+}
+
+func TestYAMLRepository_Timeout(t *testing.T) {
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "alpha.yaml"), "persona: alpha\ndescription: first\ntimeout: 5m\n")
+
+	agents, err := NewYAMLRepository(dir).ListAgents(context.Background())
+	if err != nil {
+		t.Fatalf("ListAgents error: %v", err)
+	}
+	if agents[0].Timeout != 5*time.Minute {
+		t.Fatalf("expected 5m timeout, got %s", agents[0].Timeout)
+	}
+
+	write(t, filepath.Join(dir, "alpha.yaml"), "persona: alpha\ndescription: first\ntimeout: soon\n")
+	if _, err := NewYAMLRepository(dir, Strict(true)).ListAgents(context.Background()); err == nil {
+		t.Fatal("expected error for invalid timeout")
+	}
+}
+
+func TestYAMLRepository_ToolLists(t *testing.T) {
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "alpha.yaml"), "persona: alpha\ndescription: first\nallowed_tools: [\"shell(git:*)\"]\ndenied_tools: [\"shell(rm)\", write]\n")
+
+	agents, err := NewYAMLRepository(dir).ListAgents(context.Background())
+	if err != nil {
+		t.Fatalf("ListAgents error: %v", err)
+	}
+	if len(agents[0].AllowedTools) != 1 || len(agents[0].DeniedTools) != 2 {
+		t.Fatalf("unexpected tool lists: %+v", agents[0])
+	}
+
+	write(t, filepath.Join(dir, "alpha.yaml"), "persona: alpha\ndescription: first\nallowed_tools: [write]\ndenied_tools: [write]\n")
+	if _, err := NewYAMLRepository(dir, Strict(true)).ListAgents(context.Background()); err == nil {
+		t.Fatal("expected error for tool both allowed and denied")
+	}
+}
+
+func TestYAMLRepository_TagsInherited(t *testing.T) {
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "base.yaml"), "persona: base\ndescription: base\ntags: [\" docs \", release]\ncategory: writing\n")
+	write(t, filepath.Join(dir, "child.yaml"), "extends: base\ndescription: child\n")
+
+	agents, err := NewYAMLRepository(dir).ListAgents(context.Background())
+	if err != nil {
+		t.Fatalf("ListAgents error: %v", err)
+	}
+	for _, agent := range agents {
+		if agent.Category != "writing" || len(agent.Tags) != 2 || agent.Tags[0] != "docs" {
+			t.Fatalf("unexpected tags for %s: %+v", agent.Name, agent)
+		}
+	}
+}
+
+func TestYAMLRepository_VersionAndDefinitionHash(t *testing.T) {
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "base.yaml"), "persona: base\ndescription: base\nversion: \" 1.2.0 \"\n")
+	write(t, filepath.Join(dir, "child.yaml"), "extends: base\ndescription: child\n")
+
+	load := func() map[string]Agent {
+		t.Helper()
+		list, err := NewYAMLRepository(dir).ListAgents(context.Background())
+		if err != nil {
+			t.Fatalf("ListAgents error: %v", err)
+		}
+		byName := map[string]Agent{}
+		for _, agent := range list {
+			if !strings.HasPrefix(agent.DefinitionHash, "sha256:") {
+				t.Fatalf("missing definition hash for %s: %q", agent.Name, agent.DefinitionHash)
+			}
+			byName[agent.Name] = agent
+		}
+		return byName
+	}
+
+	before := load()
+	if before["base"].Version != "1.2.0" || before["child"].Version != "" {
+		t.Fatalf("version should be trimmed and not inherited: %+v", before)
+	}
+	if again := load(); again["child"].DefinitionHash != before["child"].DefinitionHash {
+		t.Fatal("definition hash should be stable across loads")
+	}
+
+	write(t, filepath.Join(dir, "base.yaml"), "persona: base changed\ndescription: base\nversion: 1.2.0\n")
+	after := load()
+	if after["child"].DefinitionHash == before["child"].DefinitionHash {
+		t.Fatal("changing a base agent should change the child's definition hash")
+	}
+
+	write(t, filepath.Join(dir, "child.yaml"), "extends: base\ndescription: child\nenv:\n  TOKEN: one\n")
+	withEnv := load()
+	write(t, filepath.Join(dir, "child.yaml"), "extends: base\ndescription: child\nenv:\n  TOKEN: two\n")
+	if load()["child"].DefinitionHash != withEnv["child"].DefinitionHash {
+		t.Fatal("env values must not affect the definition hash")
+	}
+	if withEnv["child"].DefinitionHash == after["child"].DefinitionHash {
+		t.Fatal("adding an env key should change the definition hash")
+	}
+}
+
+func TestYAMLRepository_SkipsBrokenFiles(t *testing.T) {
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "alpha.yaml"), "persona: alpha\ndescription: first\n")
+	write(t, filepath.Join(dir, "broken.yaml"), "persona: [unclosed\n")
+	write(t, filepath.Join(dir, "child.yaml"), "extends: broken\ndescription: child\n")
+
+	repo := NewYAMLRepository(dir)
+	agents, err := repo.ListAgents(context.Background())
+	if err != nil {
+		t.Fatalf("ListAgents error: %v", err)
+	}
+	if len(agents) != 1 || agents[0].Name != "alpha" {
+		t.Fatalf("expected only alpha, got %+v", agents)
+	}
+	diags := repo.Diagnostics()
+	if len(diags) != 2 || diags[0].File != filepath.Join(dir, "broken.yaml") || diags[1].File != filepath.Join(dir, "child.yaml") {
+		t.Fatalf("unexpected diagnostics: %+v", diags)
+	}
+
+	if _, err := NewYAMLRepository(dir, Strict(true)).ListAgents(context.Background()); err == nil {
+		t.Fatal("expected strict repository to fail")
+	}
+}
+
+func TestYAMLRepository_AliasesAndDeprecation(t *testing.T) {
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "reviewer.yaml"), "persona: p\ndescription: d\naliases: [code-reviewer]\n")
+	write(t, filepath.Join(dir, "old.yaml"), "extends: reviewer\ndescription: old\ndeprecated:\n  replaced_by: \" reviewer \"\n  message: renamed\n")
+
+	agents, err := NewYAMLRepository(dir, Strict(true)).ListAgents(context.Background())
+	if err != nil {
+		t.Fatalf("ListAgents error: %v", err)
+	}
+	old := Lookup(agents, "old")
+	if old == nil || old.Deprecated == nil || old.Deprecated.ReplacedBy != "reviewer" || old.Deprecated.Message != "renamed" {
+		t.Fatalf("unexpected deprecation: %+v", old)
+	}
+	if len(old.Aliases) != 0 {
+		t.Fatalf("aliases must not be inherited: %+v", old.Aliases)
+	}
+	if Lookup(agents, "code-reviewer").Name != "reviewer" {
+		t.Fatal("expected alias lookup")
+	}
+
+	write(t, filepath.Join(dir, "self.yaml"), "persona: p\ndescription: d\naliases: [self]\n")
+	if _, err := NewYAMLRepository(dir, Strict(true)).ListAgents(context.Background()); err == nil {
+		t.Fatal("expected error for alias repeating the agent name")
+	}
+}
+
+func TestYAMLRepository_Examples(t *testing.T) {
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "alpha.yaml"), "persona: p\ndescription: d\nmax_examples: 1\nexamples:\n  - input: fix typo\n    output: Fixed a typo.\n")
+
+	agents, err := NewYAMLRepository(dir, Strict(true)).ListAgents(context.Background())
+	if err != nil {
+		t.Fatalf("ListAgents error: %v", err)
+	}
+	if len(agents[0].Examples) != 1 || agents[0].Examples[0].Output != "Fixed a typo." || agents[0].MaxExamples != 1 {
+		t.Fatalf("unexpected examples: %+v", agents[0])
+	}
+
+	write(t, filepath.Join(dir, "alpha.yaml"), "persona: p\ndescription: d\nexamples:\n  - input: fix typo\n")
+	if _, err := NewYAMLRepository(dir, Strict(true)).ListAgents(context.Background()); err == nil {
+		t.Fatal("expected error for example without output")
+	}
+}
+
 func write(t *testing.T, path, content string) {
 	t.Helper()
 	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
 		t.Fatalf("write %s: %v", path, err)
 	}
 }
+
+func TestNewProjectRepository(t *testing.T) {
+	t.Run("returns nil without project agents", func(t *testing.T) {
+		repo, err := NewProjectRepository(t.TempDir())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if repo != nil {
+			t.Fatal("expected nil repository")
+		}
+	})
+
+	t.Run("loads project agents", func(t *testing.T) {
+		workdir := t.TempDir()
+		dir := filepath.Join(workdir, ProjectAgentsDir)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		write(t, filepath.Join(dir, "local.yaml"), "persona: local\ndescription: project agent\n")
+
+		repo, err := NewProjectRepository(workdir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		agents, err := repo.ListAgents(context.Background())
+		if err != nil {
+			t.Fatalf("ListAgents error: %v", err)
+		}
+		if len(agents) != 1 || agents[0].Name != "local" || agents[0].Permissions != PermissionsReadOnly {
+			t.Fatalf("unexpected agents: %+v", agents)
+		}
+	})
+
+	t.Run("refuses untrusted env values", func(t *testing.T) {
+		workdir := t.TempDir()
+		dir := filepath.Join(workdir, ProjectAgentsDir)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		write(t, filepath.Join(dir, "leak.yaml"), "persona: p\ndescription: d\nenv: {X: file:/etc/hostname}\n")
+		write(t, filepath.Join(dir, "expand.yaml"), "persona: p\ndescription: d\nenv: {X: \"${SERVER_SECRET}\"}\n")
+		write(t, filepath.Join(dir, "preload.yaml"), "persona: p\ndescription: d\nenv: {LD_PRELOAD: /tmp/x.so}\n")
+		write(t, filepath.Join(dir, "plain.yaml"), "persona: p\ndescription: d\nenv: {GOFLAGS: -mod=mod}\n")
+
+		repo, err := NewProjectRepository(workdir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		list, err := repo.ListAgents(context.Background())
+		if err != nil {
+			t.Fatalf("ListAgents error: %v", err)
+		}
+		if len(list) != 1 || list[0].Name != "plain" {
+			t.Fatalf("expected only the plain agent, got %+v", list)
+		}
+		if diags := repo.Diagnostics(); len(diags) != 3 || !strings.Contains(diags[1].Message, "file: references are not allowed") {
+			t.Fatalf("expected env diagnostics, got %+v", diags)
+		}
+
+		trusted, err := NewProjectRepository(workdir, TrustEnv(true))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if list, err := trusted.ListAgents(context.Background()); err != nil || len(list) != 4 {
+			t.Fatalf("expected all agents when trusted, got %+v, %v", list, err)
+		}
+	})
+
+	t.Run("caps untrusted permissions", func(t *testing.T) {
+		workdir := t.TempDir()
+		dir := filepath.Join(workdir, ProjectAgentsDir)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		write(t, filepath.Join(dir, "writer.yaml"), "persona: p\ndescription: d\npermissions: workspace-write\n")
+		write(t, filepath.Join(dir, "reader.yaml"), "persona: p\ndescription: d\npermissions: read-only\n")
+
+		repo, err := NewProjectRepository(workdir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		list, err := repo.ListAgents(context.Background())
+		if err != nil || len(list) != 1 || list[0].Name != "reader" {
+			t.Fatalf("expected only the read-only agent, got %+v, %v", list, err)
+		}
+		if diags := repo.Diagnostics(); len(diags) != 1 || !strings.Contains(diags[0].Message, `limited to "read-only"`) {
+			t.Fatalf("expected permissions diagnostic, got %+v", diags)
+		}
+
+		lower := []Agent{{Name: "writer", Persona: "g", Description: "d", Permissions: PermissionsWorkspaceWrite}}
+		if list, err := repo.listAgentsOver(context.Background(), lower); err != nil || len(list) != 2 {
+			t.Fatalf("expected the shadowed profile to be allowed, got %+v, %v", list, err)
+		}
+
+		trusted, err := NewProjectRepository(workdir, TrustPermissions(true))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if list, err := trusted.ListAgents(context.Background()); err != nil || len(list) != 2 {
+			t.Fatalf("expected all agents when trusted, got %+v, %v", list, err)
+		}
+	})
+
+	t.Run("refuses agent files escaping the workdir", func(t *testing.T) {
+		workdir := t.TempDir()
+		outside := t.TempDir()
+		write(t, filepath.Join(outside, "secret.yaml"), "persona: outside\ndescription: escaped\n")
+		dir := filepath.Join(workdir, ProjectAgentsDir)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.Symlink(filepath.Join(outside, "secret.yaml"), filepath.Join(dir, "secret.yaml")); err != nil {
+			t.Fatalf("symlink: %v", err)
+		}
+
+		repo, err := NewProjectRepository(workdir, Strict(true))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := repo.ListAgents(context.Background()); err == nil {
+			t.Fatal("expected symlink escape error")
+		}
+	})
+
+	t.Run("refuses agents dir escaping the workdir", func(t *testing.T) {
+		workdir := t.TempDir()
+		if err := os.Mkdir(filepath.Join(workdir, ".subagents"), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.Symlink(t.TempDir(), filepath.Join(workdir, ProjectAgentsDir)); err != nil {
+			t.Fatalf("symlink: %v", err)
+		}
+		if _, err := NewProjectRepository(workdir); err == nil {
+			t.Fatal("expected symlink escape error")
+		}
+	})
+}
+
+func TestYAMLRepository_Runners(t *testing.T) {
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "alpha.yaml"), "persona: alpha\ndescription: first\nrunners: [\" gemini \", codex, \"\"]\n")
+
+	agents, err := NewYAMLRepository(dir).ListAgents(context.Background())
+	if err != nil {
+		t.Fatalf("ListAgents error: %v", err)
+	}
+	if len(agents[0].Runners) != 2 || agents[0].Runners[0] != "gemini" || agents[0].Runners[1] != "codex" {
+		t.Fatalf("unexpected runners: %q", agents[0].Runners)
+	}
+}
+
+func TestYAMLRepository_PersonaTemplate(t *testing.T) {
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "literal.yaml"), "persona: \"Use {{ as is\"\ndescription: d\n")
+	write(t, filepath.Join(dir, "templated.yaml"), "persona: \"Use {{ as is\"\ndescription: d\npersona_template: true\n")
+
+	list, err := NewYAMLRepository(dir).ListAgents(context.Background())
+	if err != nil {
+		t.Fatalf("ListAgents error: %v", err)
+	}
+	if len(list) != 1 || list[0].Name != "literal" {
+		t.Fatalf("expected only the literal persona to load, got %+v", list)
+	}
+}
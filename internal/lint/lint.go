@@ -0,0 +1,170 @@
+// Package lint checks agent directories against the runner configuration and
+// aggregates every problem into a single report.
+package lint
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"go.uber.org/zap"
+
+	"subagents-mcp/internal/agents"
+	"subagents-mcp/internal/runner"
+)
+
+// Options selects what to lint.
+type Options struct {
+	// Packs are agent packs in --agent-pack order, layered below Dirs.
+	Packs []agents.PackSpec
+	// Dirs are agent directories in --agents-dir order.
+	Dirs []string
+	// Config and Runner mirror the server's --runner-config and --runner.
+	Config runner.Config
+	Runner string
+}
+
+// Report aggregates diagnostics from every directory.
+type Report struct {
+	Diagnostics []agents.Diagnostic `json:"diagnostics"`
+}
+
+// Errors counts error diagnostics.
+func (r Report) Errors() int {
+	return r.count(agents.SeverityError)
+}
+
+// Warnings counts warning diagnostics.
+func (r Report) Warnings() int {
+	return r.count(agents.SeverityWarning)
+}
+
+func (r Report) count(severity string) int {
+	n := 0
+	for _, d := range r.Diagnostics {
+		if d.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+// Write prints one line per diagnostic followed by a summary.
+func (r Report) Write(w io.Writer) error {
+	for _, d := range r.Diagnostics {
+		if _, err := fmt.Fprintln(w, d.String()); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d error(s), %d warning(s)\n", r.Errors(), r.Warnings())
+	return err
+}
+
+// Run lints every pack in opts.Packs and directory in opts.Dirs. On top of
+// the per-file checks of agents.LintDir it reports agents shadowed by a later
+// source and models that no configured runner serves.
+func Run(ctx context.Context, opts Options) (Report, error) {
+	selector, err := runner.NewSelector(zap.NewNop(), opts.Config, opts.Runner)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{Diagnostics: []agents.Diagnostic{}}
+	definedIn := make(map[string]string)
+	var catalog []agents.Agent
+	position := make(map[string]int)
+	merge := func(loaded []agents.Agent, diags []agents.Diagnostic, fileOf func(name string) string) {
+		report.Diagnostics = append(report.Diagnostics, diags...)
+		for _, agent := range loaded {
+			file := fileOf(agent.Name)
+			if previous, ok := definedIn[agent.Name]; ok {
+				report.add(previous, agents.SeverityWarning, "agent %q is shadowed by %s", agent.Name, file)
+			}
+			definedIn[agent.Name] = file
+			if pos, ok := position[agent.Name]; ok {
+				catalog[pos] = agent
+			} else {
+				position[agent.Name] = len(catalog)
+				catalog = append(catalog, agent)
+			}
+			report.Diagnostics = append(report.Diagnostics, checkRouting(selector, agent, file)...)
+		}
+	}
+	for _, spec := range opts.Packs {
+		pack, err := agents.NewPackRepository(ctx, spec)
+		if err != nil {
+			report.add(spec.String(), agents.SeverityError, "%v", err)
+			continue
+		}
+		loaded, diags := pack.Lint(ctx, catalog...)
+		merge(loaded, diags, pack.File)
+		if err := pack.Close(); err != nil {
+			return Report{}, err
+		}
+	}
+	for _, dir := range opts.Dirs {
+		loaded, diags := agents.LintDir(ctx, dir, catalog...)
+		merge(loaded, diags, func(name string) string { return filepath.Join(dir, name+".yaml") })
+	}
+	report.Diagnostics = append(report.Diagnostics, checkNames(catalog, definedIn)...)
+	return report, nil
+}
+
+// checkNames reports aliases that collide with other agents and deprecation
+// redirects that point nowhere, across the merged catalog.
+func checkNames(catalog []agents.Agent, files map[string]string) []agents.Diagnostic {
+	var diags []agents.Diagnostic
+	report := func(agent, severity, format string, args ...any) {
+		diags = append(diags, agents.Diagnostic{File: files[agent], Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	owners := make(map[string]string)
+	for _, agent := range catalog {
+		for _, alias := range agent.Aliases {
+			if _, ok := files[alias]; ok {
+				report(agent.Name, agents.SeverityWarning, "alias %q is unreachable because agent %q has that name", alias, alias)
+				continue
+			}
+			if owner, ok := owners[alias]; ok {
+				report(agent.Name, agents.SeverityError, "alias %q is already used by agent %q", alias, owner)
+				continue
+			}
+			owners[alias] = agent.Name
+		}
+		if agent.Deprecated != nil && agent.Deprecated.ReplacedBy != "" && agents.Lookup(catalog, agent.Deprecated.ReplacedBy) == nil {
+			report(agent.Name, agents.SeverityError, "replacement %q of deprecated agent %q does not exist", agent.Deprecated.ReplacedBy, agent.Name)
+		}
+	}
+	return diags
+}
+
+// checkRouting reports models of agent that no runner would serve.
+func checkRouting(planner runner.Planner, agent agents.Agent, file string) []agents.Diagnostic {
+	routes, err := planner.Plan(agent, agent.Model)
+	if err != nil {
+		return []agents.Diagnostic{{File: file, Severity: agents.SeverityError, Message: err.Error()}}
+	}
+	served := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		served[route.Model] = true
+	}
+
+	var diags []agents.Diagnostic
+	for _, model := range append([]string{agent.Model}, agent.Models...) {
+		if model == "" || served[model] {
+			continue
+		}
+		served[model] = true
+		diags = append(diags, agents.Diagnostic{
+			File:     file,
+			Severity: agents.SeverityError,
+			Message:  fmt.Sprintf("model %q is not served by any configured runner", model),
+		})
+	}
+	return diags
+}
+
+func (r *Report) add(file, severity, format string, args ...any) {
+	r.Diagnostics = append(r.Diagnostics, agents.Diagnostic{File: file, Severity: severity, Message: fmt.Sprintf(format, args...)})
+}
@@ -0,0 +1,126 @@
+package lint
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"subagents-mcp/internal/agents"
+	"subagents-mcp/internal/runner"
+)
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	shared, local := t.TempDir(), t.TempDir()
+	write(t, filepath.Join(shared, "reviewer.yaml"), "persona: p\ndescription: d\nmodel: gpt-5\n")
+	write(t, filepath.Join(shared, "typo.yaml"), "persona: p\ndescripton: d\n")
+	write(t, filepath.Join(local, "reviewer.yaml"), "persona: p\ndescription: d\nmodel: gpt-5\nmodels: [claude-sonnet-4.5, llama]\n")
+
+	cfg := runner.Config{Runners: []runner.RunnerConfig{
+		{Name: "codex", Priority: 1, Models: []string{"gpt-5"}},
+		{Name: "copilot", Priority: 2, Models: []string{"claude-sonnet-4.5"}},
+	}}
+	report, err := Run(context.Background(), Options{Dirs: []string{shared, local}, Config: cfg})
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := report.Write(&out); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	text := out.String()
+	for _, want := range []string{
+		"typo.yaml:2:1: error: unknown field \"descripton\"",
+		filepath.Join(shared, "reviewer.yaml") + ": warning: agent \"reviewer\" is shadowed by " + filepath.Join(local, "reviewer.yaml"),
+		filepath.Join(local, "reviewer.yaml") + ": error: model \"llama\" is not served by any configured runner",
+		"2 error(s), 1 warning(s)",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in report:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, `model "gpt-5"`) || strings.Contains(text, "claude-sonnet-4.5\" is not served") {
+		t.Fatalf("served models should not be reported:\n%s", text)
+	}
+}
+
+func TestRunLintsAgentPacks(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"agents/reviewer.yaml": "persona: p\ndescription: d\n",
+		"agents/typo.yaml":     "persona: p\ndescripton: d\n",
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(t.TempDir(), "pack.zip")
+	if err := os.WriteFile(archive, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	local := t.TempDir()
+	write(t, filepath.Join(local, "reviewer.yaml"), "extends: reviewer\ndescription: local\n")
+
+	pack := agents.PackSpec{Kind: agents.PackArchive, Path: archive, Dir: "agents"}
+	report, err := Run(context.Background(), Options{Packs: []agents.PackSpec{pack}, Dirs: []string{local}})
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	var out bytes.Buffer
+	if err := report.Write(&out); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	text := out.String()
+	for _, want := range []string{
+		archive + "/agents:typo.yaml:2:1: error: unknown field \"descripton\"",
+		archive + "/agents:reviewer.yaml: warning: agent \"reviewer\" is shadowed by " + filepath.Join(local, "reviewer.yaml"),
+		"1 error(s), 1 warning(s)",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in report:\n%s", want, text)
+		}
+	}
+}
+
+func TestRunChecksAliasesAndReplacements(t *testing.T) {
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "a.yaml"), "persona: p\ndescription: d\naliases: [shared, b]\n")
+	write(t, filepath.Join(dir, "b.yaml"), "persona: p\ndescription: d\naliases: [shared]\ndeprecated:\n  replaced_by: nowhere\n")
+
+	report, err := Run(context.Background(), Options{Dirs: []string{dir}})
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	var out bytes.Buffer
+	if err := report.Write(&out); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	for _, want := range []string{
+		`a.yaml: warning: alias "b" is unreachable because agent "b" has that name`,
+		`b.yaml: error: alias "shared" is already used by agent "a"`,
+		`b.yaml: error: replacement "nowhere" of deprecated agent "b" does not exist`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in report:\n%s", want, out.String())
+		}
+	}
+}
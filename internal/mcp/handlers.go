@@ -4,42 +4,221 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
 
 	"subagents-mcp/internal/agents"
 	"subagents-mcp/internal/runner"
+	"subagents-mcp/internal/schema"
 	"subagents-mcp/internal/validate"
 )
 
 type Handlers struct {
-	repo   agents.Repository
-	runner runner.AgentRunner
-	logger *zap.Logger
+	repo         agents.Repository
+	runner       runner.AgentRunner
+	logger       *zap.Logger
+	allowedRoots []string
+	showPersona  bool
+	store        *agents.Store
+	strict       bool
+	trustEnv     bool
+	trustPerms   bool
+
+	mu sync.Mutex
+	// logged holds the problems last logged per agent file.
+	logged map[string]string
+}
+
+// maxLoggedFiles bounds logged; project directories make the set of files
+// open-ended, so it is reset when full.
+const maxLoggedFiles = 1024
+
+// Option configures optional Handlers behavior.
+type Option func(*Handlers)
+
+// WithAllowedRoots restricts delegation working directories to the given
+// root globs for every agent.
+func WithAllowedRoots(roots []string) Option {
+	return func(h *Handlers) {
+		h.allowedRoots = roots
+	}
+}
+
+// WithPersonaDisclosure lets describe_agent return persona text.
+func WithPersonaDisclosure(enabled bool) Option {
+	return func(h *Handlers) {
+		h.showPersona = enabled
+	}
+}
+
+// WithAgentStore enables the create_agent, update_agent and delete_agent
+// tools, writing through store.
+func WithAgentStore(store *agents.Store) Option {
+	return func(h *Handlers) {
+		h.store = store
+	}
+}
+
+// WithStrictAgents makes project agent directories fail on the first broken
+// file, matching --strict-agents for the global catalog.
+func WithStrictAgents(enabled bool) Option {
+	return func(h *Handlers) {
+		h.strict = enabled
+	}
+}
+
+// WithTrustedProjectEnv lets project agents use file: references, ${VAR}
+// expansion and loader variables in env, like agents from --agents-dir.
+func WithTrustedProjectEnv(enabled bool) Option {
+	return func(h *Handlers) {
+		h.trustEnv = enabled
+	}
+}
+
+// WithTrustedProjectPermissions lets project agents request any permission
+// profile instead of read-only or the profile of the agent they shadow or
+// extend.
+func WithTrustedProjectPermissions(enabled bool) Option {
+	return func(h *Handlers) {
+		h.trustPerms = enabled
+	}
+}
+
+func NewHandlers(repo agents.Repository, runner runner.AgentRunner, logger *zap.Logger, opts ...Option) *Handlers {
+	h := &Handlers{repo: repo, runner: runner, logger: logger, logged: make(map[string]string)}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
-func NewHandlers(repo agents.Repository, runner runner.AgentRunner, logger *zap.Logger) *Handlers {
-	return &Handlers{repo: repo, runner: runner, logger: logger}
+type listAgentsArgs struct {
+	WorkingDirectory string   `json:"working_directory"`
+	Query            string   `json:"query"`
+	Tags             []string `json:"tags"`
+	Category         string   `json:"category"`
+	// IncludeDeprecated lists deprecated agents too.
+	IncludeDeprecated bool `json:"include_deprecated"`
+	// Verbose also lists agents whose requirements are unmet, with a reason.
+	Verbose bool `json:"verbose"`
 }
 
-type listAgentsResult struct {
+// textResult is the result of tools that reply with one JSON text item.
+type textResult struct {
 	Content []contentItem `json:"content"`
 }
 
 // agentSummary exposes only the public metadata for an agent.
 type agentSummary struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	Name           string              `json:"name"`
+	Description    string              `json:"description"`
+	Source         string              `json:"source,omitempty"`
+	Category       string              `json:"category,omitempty"`
+	Tags           []string            `json:"tags,omitempty"`
+	Aliases        []string            `json:"aliases,omitempty"`
+	Deprecated     *agents.Deprecation `json:"deprecated,omitempty"`
+	Version        string              `json:"version,omitempty"`
+	DefinitionHash string              `json:"definition_hash,omitempty"`
+	// FileHash is the managed file's hash, the expected_hash for
+	// update_agent and delete_agent; set only for agents in the store.
+	FileHash    string         `json:"file_hash,omitempty"`
+	Inputs      map[string]any `json:"inputs,omitempty"`
+	Permissions string         `json:"permissions,omitempty"`
+	Tools       *toolPolicy    `json:"tools,omitempty"`
+	// Unavailable explains unmet requirements; empty when the agent can run.
+	Unavailable string `json:"unavailable,omitempty"`
 }
 
-type delegateArgs struct {
+// toolPolicy reports the tool restrictions applied to an agent.
+type toolPolicy struct {
+	Allowed []string `json:"allowed,omitempty"`
+	Denied  []string `json:"denied,omitempty"`
+}
+
+type catalogStatusArgs struct {
+	WorkingDirectory string `json:"working_directory"`
+}
+
+// catalogStatus reports how many agents loaded and which definitions were
+// skipped.
+type catalogStatus struct {
+	Agents      int                 `json:"agents"`
+	Strict      bool                `json:"strict"`
+	Diagnostics []agents.Diagnostic `json:"diagnostics"`
+}
+
+type describeAgentArgs struct {
 	Agent            string `json:"agent"`
-	Task             string `json:"task"`
 	WorkingDirectory string `json:"working_directory"`
 }
 
+// agentDetails is the full description of an agent returned by describe_agent.
+type agentDetails struct {
+	agentSummary
+	Model        string               `json:"model,omitempty"`
+	Models       []string             `json:"models,omitempty"`
+	Runners      []string             `json:"runners,omitempty"`
+	Requires     *agents.Requirements `json:"requires,omitempty"`
+	Examples     []agents.Example     `json:"examples,omitempty"`
+	OutputSchema map[string]any       `json:"output_schema,omitempty"`
+	Timeout      string               `json:"timeout,omitempty"`
+	Routing      []runner.Route       `json:"routing,omitempty"`
+	RoutingError string               `json:"routing_error,omitempty"`
+	Persona      string               `json:"persona,omitempty"`
+}
+
+type writeAgentArgs struct {
+	Name         string         `json:"name"`
+	Definition   map[string]any `json:"definition"`
+	ExpectedHash string         `json:"expected_hash"`
+}
+
+type deleteAgentArgs struct {
+	Name         string `json:"name"`
+	ExpectedHash string `json:"expected_hash"`
+}
+
+// agentWriteResult reports the file a management tool touched.
+type agentWriteResult struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Hash    string `json:"hash,omitempty"`
+	Deleted bool   `json:"deleted,omitempty"`
+}
+
+type delegateArgs struct {
+	Agent            string         `json:"agent"`
+	Task             string         `json:"task"`
+	WorkingDirectory string         `json:"working_directory"`
+	Variables        map[string]any `json:"variables"`
+	Inputs           map[string]any `json:"inputs"`
+	TimeoutSeconds   int            `json:"timeout_seconds"`
+	// AgentVersion pins the call to an agent version or content hash.
+	AgentVersion string `json:"agent_version"`
+}
+
 type delegateResult struct {
-	Content []contentItem `json:"content"`
+	Content           []contentItem `json:"content"`
+	StructuredContent any           `json:"structuredContent,omitempty"`
+	Meta              *delegateMeta `json:"_meta,omitempty"`
+}
+
+// delegateMeta describes how a delegation was served.
+type delegateMeta struct {
+	Runner       string   `json:"runner,omitempty"`
+	Model        string   `json:"model,omitempty"`
+	ContextFiles []string `json:"context_files,omitempty"`
+	// Agent is the agent that served the call when it differs from the
+	// requested name because of an alias or deprecation redirect.
+	Agent          string   `json:"agent,omitempty"`
+	AgentVersion   string   `json:"agent_version,omitempty"`
+	DefinitionHash string   `json:"definition_hash,omitempty"`
+	Warnings       []string `json:"warnings,omitempty"`
 }
 
 type contentItem struct {
@@ -47,24 +226,92 @@ type contentItem struct {
 	Text string `json:"text,omitempty"`
 }
 
-func (h *Handlers) ListAgents(ctx context.Context) (listAgentsResult, error) {
-	agentsList, err := h.repo.ListAgents(ctx)
+func (h *Handlers) ListAgents(ctx context.Context, args listAgentsArgs) (textResult, error) {
+	workdir, err := h.optionalWorkdir(args.WorkingDirectory)
 	if err != nil {
-		return listAgentsResult{}, err
+		return textResult{}, err
 	}
+
+	agentsList, err := h.catalog(ctx, workdir)
+	if err != nil {
+		return textResult{}, err
+	}
+	agentsList = agents.Search(agentsList, agents.Query{Text: args.Query, Tags: args.Tags, Category: args.Category})
 	summaries := make([]agentSummary, 0, len(agentsList))
 	for _, agent := range agentsList {
-		summaries = append(summaries, agentSummary{
-			Name:        agent.Name,
-			Description: agent.Description,
-		})
+		if agent.Deprecated != nil && !args.IncludeDeprecated {
+			continue
+		}
+		summary := summarize(agent)
+		summary.FileHash = h.fileHash(agent)
+		if err := h.checkRequirements(agent, workdir); err != nil {
+			if !args.Verbose {
+				continue
+			}
+			summary.Unavailable = err.Error()
+		}
+		summaries = append(summaries, summary)
 	}
 
 	payload, err := json.Marshal(map[string]any{"agents": summaries})
 	if err != nil {
-		return listAgentsResult{}, fmt.Errorf("marshal agents: %w", err)
+		return textResult{}, fmt.Errorf("marshal agents: %w", err)
+	}
+	return textResult{Content: []contentItem{{Type: "text", Text: string(payload)}}}, nil
+}
+
+// DescribeAgent returns an agent's full metadata together with the ordered
+// (runner, model) pairs delegation would try.
+func (h *Handlers) DescribeAgent(ctx context.Context, args describeAgentArgs) (textResult, error) {
+	if args.Agent == "" {
+		return textResult{}, fmt.Errorf("agent is required")
+	}
+	workdir, err := h.optionalWorkdir(args.WorkingDirectory)
+	if err != nil {
+		return textResult{}, err
+	}
+
+	agentsList, err := h.catalog(ctx, workdir)
+	if err != nil {
+		return textResult{}, err
+	}
+	selected := agents.Lookup(agentsList, args.Agent)
+	if selected == nil {
+		return textResult{}, fmt.Errorf("agent %q not found", args.Agent)
+	}
+
+	details := agentDetails{
+		agentSummary: summarize(*selected),
+		Model:        selected.Model,
+		Models:       selected.Models,
+		Runners:      selected.Runners,
+		Requires:     selected.Requires,
+		Examples:     selected.Examples,
+		OutputSchema: selected.OutputSchema,
+	}
+	details.FileHash = h.fileHash(*selected)
+	if err := h.checkRequirements(*selected, workdir); err != nil {
+		details.Unavailable = err.Error()
+	}
+	if selected.Timeout > 0 {
+		details.Timeout = selected.Timeout.String()
+	}
+	if planner, ok := h.runner.(runner.Planner); ok {
+		routes, err := planner.Plan(*selected, selected.Model)
+		if err != nil {
+			details.RoutingError = err.Error()
+		}
+		details.Routing = routes
+	}
+	if h.showPersona {
+		details.Persona = selected.Persona
+	}
+
+	payload, err := json.Marshal(details)
+	if err != nil {
+		return textResult{}, fmt.Errorf("marshal agent: %w", err)
 	}
-	return listAgentsResult{Content: []contentItem{{Type: "text", Text: string(payload)}}}, nil
+	return textResult{Content: []contentItem{{Type: "text", Text: string(payload)}}}, nil
 }
 
 func (h *Handlers) DelegateTask(ctx context.Context, args delegateArgs) (delegateResult, error) {
@@ -74,33 +321,331 @@ func (h *Handlers) DelegateTask(ctx context.Context, args delegateArgs) (delegat
 	if args.Task == "" {
 		return delegateResult{}, fmt.Errorf("task is required")
 	}
+	if args.TimeoutSeconds < 0 {
+		return delegateResult{}, fmt.Errorf("timeout_seconds must not be negative")
+	}
 	workdir, err := validate.Dir(args.WorkingDirectory)
 	if err != nil {
 		return delegateResult{}, fmt.Errorf("working_directory invalid: %w", err)
 	}
+	if len(h.allowedRoots) > 0 && !validate.UnderRoots(workdir, h.allowedRoots) {
+		return delegateResult{}, fmt.Errorf("working_directory %q is outside the server --allowed-root list %q", workdir, h.allowedRoots)
+	}
+
+	agentsList, err := h.catalog(ctx, workdir)
+	if err != nil {
+		return delegateResult{}, err
+	}
 
-	agentsList, err := h.repo.ListAgents(ctx)
+	selected, warnings, err := agents.Resolve(agentsList, args.Agent)
 	if err != nil {
 		return delegateResult{}, err
 	}
+	for _, warning := range warnings {
+		h.logger.Warn("agent redirected", zap.String("requested", args.Agent), zap.String("agent", selected.Name), zap.String("reason", warning))
+	}
+	if len(selected.AllowedRoots) > 0 && !validate.UnderRoots(workdir, selected.AllowedRoots) {
+		return delegateResult{}, fmt.Errorf("working_directory %q is outside allowed_roots %q of agent %q", workdir, selected.AllowedRoots, selected.Name)
+	}
+	if err := h.checkRequirements(*selected, workdir); err != nil {
+		return delegateResult{}, fmt.Errorf("agent %q is unavailable: %w", selected.Name, err)
+	}
+	if err := checkPinnedVersion(*selected, args.AgentVersion); err != nil {
+		return delegateResult{}, err
+	}
 
-	var selected *agents.Agent
-	for i := range agentsList {
-		if agentsList[i].Name == args.Agent {
-			selected = &agentsList[i]
-			break
+	inputs := args.Inputs
+	if inputs == nil {
+		inputs = map[string]any{}
+	}
+	if selected.Inputs != nil {
+		if err := schema.Validate(selected.Inputs, inputs); err != nil {
+			return delegateResult{}, fmt.Errorf("inputs invalid for agent %q: %w", selected.Name, err)
 		}
 	}
-	if selected == nil {
-		return delegateResult{}, fmt.Errorf("agent %q not found", args.Agent)
+	task, err := runner.TaskWithInputs(args.Task, inputs)
+	if err != nil {
+		return delegateResult{}, err
 	}
 
-	output, err := h.runner.Run(ctx, *selected, args.Task, workdir, selected.Model)
+	vars := runner.PromptVariables(ctx, workdir, args.Variables)
+	vars["Inputs"] = inputs
+	agent, err := runner.RenderPersona(*selected, vars)
 	if err != nil {
 		return delegateResult{}, err
 	}
+	if args.TimeoutSeconds > 0 {
+		agent.Timeout = time.Duration(args.TimeoutSeconds) * time.Second
+	}
+
+	report := &runner.Report{}
+	ctx = runner.WithPromptVariables(runner.WithReport(ctx, report), vars)
+	output, structured, err := runner.RunWithOutputContract(ctx, h.logger, h.runner, agent, task, workdir)
+	if err != nil {
+		return delegateResult{}, err
+	}
+
+	result := delegateResult{Content: []contentItem{{Type: "text", Text: output}}}
+	if structured != nil {
+		payload, err := json.Marshal(structured)
+		if err != nil {
+			return delegateResult{}, fmt.Errorf("marshal structured output: %w", err)
+		}
+		result.Content = []contentItem{{Type: "text", Text: string(payload)}}
+		result.StructuredContent = structured
+	}
+	result.Meta = &delegateMeta{
+		Runner:         report.Runner,
+		Model:          report.Model,
+		ContextFiles:   report.ContextFiles,
+		AgentVersion:   selected.Version,
+		DefinitionHash: selected.DefinitionHash,
+		Warnings:       warnings,
+	}
+	if selected.Name != args.Agent {
+		result.Meta.Agent = selected.Name
+	}
+	return result, nil
+}
+
+// checkPinnedVersion fails when the caller pinned a version ("sha256:..."
+// pins the content hash) that the agent no longer has.
+func checkPinnedVersion(agent agents.Agent, pinned string) error {
+	pinned = strings.TrimSpace(pinned)
+	if pinned == "" {
+		return nil
+	}
+	current := agent.Version
+	if strings.HasPrefix(pinned, "sha256:") {
+		current = agent.DefinitionHash
+	}
+	if current != pinned {
+		return fmt.Errorf("agent %q changed: pinned %q, current version %q (definition_hash %s)", agent.Name, pinned, agent.Version, agent.DefinitionHash)
+	}
+	return nil
+}
+
+// CreateAgent writes a new agent definition into the writable agents dir.
+func (h *Handlers) CreateAgent(ctx context.Context, args writeAgentArgs) (textResult, error) {
+	if h.store == nil {
+		return textResult{}, fmt.Errorf("agent management is disabled")
+	}
+	content, err := definitionYAML(args.Definition)
+	if err != nil {
+		return textResult{}, err
+	}
+	hash, err := h.store.Create(ctx, args.Name, content)
+	if err != nil {
+		return textResult{}, err
+	}
+	h.logger.Info("agent created", zap.String("agent", args.Name), zap.String("hash", hash))
+	return writeResult(agentWriteResult{Name: args.Name, Path: filepath.Join(h.store.Dir(), args.Name+".yaml"), Hash: hash})
+}
+
+// UpdateAgent replaces an agent definition if it still matches expected_hash.
+func (h *Handlers) UpdateAgent(ctx context.Context, args writeAgentArgs) (textResult, error) {
+	if h.store == nil {
+		return textResult{}, fmt.Errorf("agent management is disabled")
+	}
+	content, err := definitionYAML(args.Definition)
+	if err != nil {
+		return textResult{}, err
+	}
+	hash, err := h.store.Update(ctx, args.Name, content, args.ExpectedHash)
+	if err != nil {
+		return textResult{}, err
+	}
+	h.logger.Info("agent updated", zap.String("agent", args.Name), zap.String("hash", hash))
+	return writeResult(agentWriteResult{Name: args.Name, Path: filepath.Join(h.store.Dir(), args.Name+".yaml"), Hash: hash})
+}
+
+// DeleteAgent removes an agent definition if it still matches expected_hash.
+func (h *Handlers) DeleteAgent(ctx context.Context, args deleteAgentArgs) (textResult, error) {
+	if h.store == nil {
+		return textResult{}, fmt.Errorf("agent management is disabled")
+	}
+	if err := h.store.Delete(ctx, args.Name, args.ExpectedHash); err != nil {
+		return textResult{}, err
+	}
+	h.logger.Info("agent deleted", zap.String("agent", args.Name))
+	return writeResult(agentWriteResult{Name: args.Name, Path: filepath.Join(h.store.Dir(), args.Name+".yaml"), Deleted: true})
+}
+
+// definitionYAML renders a tool-supplied definition object as agent YAML.
+func definitionYAML(definition map[string]any) ([]byte, error) {
+	if len(definition) == 0 {
+		return nil, fmt.Errorf("definition is required")
+	}
+	content, err := yaml.Marshal(definition)
+	if err != nil {
+		return nil, fmt.Errorf("encode definition: %w", err)
+	}
+	return content, nil
+}
+
+func writeResult(result agentWriteResult) (textResult, error) {
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return textResult{}, fmt.Errorf("marshal result: %w", err)
+	}
+	return textResult{Content: []contentItem{{Type: "text", Text: string(payload)}}}, nil
+}
 
-	return delegateResult{Content: []contentItem{{Type: "text", Text: output}}}, nil
+// optionalWorkdir validates an optional working_directory argument; an empty
+// value yields an empty workdir.
+func (h *Handlers) optionalWorkdir(dir string) (string, error) {
+	if dir == "" {
+		return "", nil
+	}
+	resolved, err := validate.Dir(dir)
+	if err != nil {
+		return "", fmt.Errorf("working_directory invalid: %w", err)
+	}
+	if len(h.allowedRoots) > 0 && !validate.UnderRoots(resolved, h.allowedRoots) {
+		return "", fmt.Errorf("working_directory %q is outside the server --allowed-root list %q", resolved, h.allowedRoots)
+	}
+	return resolved, nil
+}
+
+// CatalogStatus reports the loaded agent count and the diagnostics of agent
+// files that were skipped.
+func (h *Handlers) CatalogStatus(ctx context.Context, args catalogStatusArgs) (textResult, error) {
+	workdir, err := h.optionalWorkdir(args.WorkingDirectory)
+	if err != nil {
+		return textResult{}, err
+	}
+	repo, err := h.source(workdir)
+	if err != nil {
+		return textResult{}, err
+	}
+	agentsList, err := repo.ListAgents(ctx)
+	if err != nil {
+		return textResult{}, err
+	}
+	status := catalogStatus{Agents: len(agentsList), Strict: h.strict, Diagnostics: []agents.Diagnostic{}}
+	if reporter, ok := repo.(agents.DiagnosticsReporter); ok {
+		status.Diagnostics = append(status.Diagnostics, reporter.Diagnostics()...)
+	}
+
+	payload, err := json.Marshal(status)
+	if err != nil {
+		return textResult{}, fmt.Errorf("marshal status: %w", err)
+	}
+	return textResult{Content: []contentItem{{Type: "text", Text: string(payload)}}}, nil
+}
+
+// catalog returns the agents visible for a call. When workdir is set, agents
+// defined under its ProjectAgentsDir are layered over the global catalog.
+// Skipped agent files are logged again only when their problems change.
+func (h *Handlers) catalog(ctx context.Context, workdir string) ([]agents.Agent, error) {
+	repo, err := h.source(workdir)
+	if err != nil {
+		return nil, err
+	}
+	agentsList, err := repo.ListAgents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if reporter, ok := repo.(agents.DiagnosticsReporter); ok {
+		h.logDiagnostics(reporter.Diagnostics())
+	}
+	// Repositories other than the YAML ones may not fingerprint agents.
+	hashed := make([]agents.Agent, len(agentsList))
+	for i, agent := range agentsList {
+		if agent.DefinitionHash == "" {
+			agent.DefinitionHash = agents.Fingerprint(agent)
+		}
+		hashed[i] = agent
+	}
+	return hashed, nil
+}
+
+func (h *Handlers) source(workdir string) (agents.Repository, error) {
+	if workdir == "" {
+		return h.repo, nil
+	}
+	project, err := agents.NewProjectRepository(workdir, agents.Strict(h.strict), agents.TrustEnv(h.trustEnv), agents.TrustPermissions(h.trustPerms))
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return h.repo, nil
+	}
+	return agents.NewCompositeRepository(h.logger, h.repo, project), nil
+}
+
+func (h *Handlers) logDiagnostics(diags []agents.Diagnostic) {
+	byFile := make(map[string][]agents.Diagnostic)
+	var files []string
+	for _, d := range diags {
+		if _, ok := byFile[d.File]; !ok {
+			files = append(files, d.File)
+		}
+		byFile[d.File] = append(byFile[d.File], d)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, file := range files {
+		problems := make([]string, 0, len(byFile[file]))
+		for _, d := range byFile[file] {
+			problems = append(problems, d.String())
+		}
+		key := strings.Join(problems, "\n")
+		if h.logged[file] == key {
+			continue
+		}
+		if len(h.logged) >= maxLoggedFiles {
+			clear(h.logged)
+		}
+		h.logged[file] = key
+		for _, d := range byFile[file] {
+			h.logger.Warn("agent definition skipped",
+				zap.String("file", d.File),
+				zap.Int("line", d.Line),
+				zap.String("problem", d.Message))
+		}
+	}
+}
+
+// checkRequirements evaluates agent.Requires against the configured runner,
+// which also decides runner and model conditions when it can plan routes.
+func (h *Handlers) checkRequirements(agent agents.Agent, workdir string) error {
+	planner, _ := h.runner.(runner.Planner)
+	return runner.CheckRequirements(agent, workdir, planner)
+}
+
+// fileHash returns the store hash of agent's file when the agent was loaded
+// from the managed directory, and "" otherwise.
+func (h *Handlers) fileHash(agent agents.Agent) string {
+	if h.store == nil || agent.Source != h.store.Dir() {
+		return ""
+	}
+	hash, err := h.store.Hash(agent.Name)
+	if err != nil {
+		return ""
+	}
+	return hash
+}
+
+func summarize(agent agents.Agent) agentSummary {
+	summary := agentSummary{
+		Name:           agent.Name,
+		Description:    agent.Description,
+		Source:         agent.Source,
+		Category:       agent.Category,
+		Tags:           agent.Tags,
+		Aliases:        agent.Aliases,
+		Deprecated:     agent.Deprecated,
+		Version:        agent.Version,
+		DefinitionHash: agent.DefinitionHash,
+		Inputs:         agent.Inputs,
+		Permissions:    agent.Permissions,
+	}
+	if len(agent.AllowedTools) > 0 || len(agent.DeniedTools) > 0 {
+		summary.Tools = &toolPolicy{Allowed: agent.AllowedTools, Denied: agent.DeniedTools}
+	}
+	return summary
 }
 
 func decodeArgs[T any](raw json.RawMessage) (T, error) {
@@ -113,3 +658,15 @@ func decodeArgs[T any](raw json.RawMessage) (T, error) {
 	}
 	return args, nil
 }
+
+// decodeOptionalArgs is like decodeArgs but accepts missing arguments.
+func decodeOptionalArgs[T any](raw json.RawMessage) (T, error) {
+	var args T
+	if len(raw) == 0 || string(raw) == "null" {
+		return args, nil
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return args, err
+	}
+	return args, nil
+}
@@ -4,11 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"subagents-mcp/internal/agents"
+	"subagents-mcp/internal/runner"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 type stubRepo struct {
@@ -30,10 +37,10 @@ func (s stubRunner) Run(ctx context.Context, agent agents.Agent, task string, wo
 }
 
 func TestListAgentsHandler(t *testing.T) {
-	repo := stubRepo{agents: []agents.Agent{{Name: "a", Persona: "p", Description: "d"}}}
+	repo := stubRepo{agents: []agents.Agent{{Name: "a", Persona: "p", Description: "d", Source: "/agents"}}}
 	h := NewHandlers(repo, stubRunner{}, zap.NewNop())
 
-	result, err := h.ListAgents(context.Background())
+	result, err := h.ListAgents(context.Background(), listAgentsArgs{})
 	if err != nil {
 		t.Fatalf("ListAgents error: %v", err)
 	}
@@ -47,6 +54,7 @@ func TestListAgentsHandler(t *testing.T) {
 		Agents []struct {
 			Name        string  `json:"name"`
 			Description string  `json:"description"`
+			Source      string  `json:"source"`
 			Persona     *string `json:"persona,omitempty"`
 		} `json:"agents"`
 	}
@@ -56,6 +64,9 @@ func TestListAgentsHandler(t *testing.T) {
 	if len(payload.Agents) != 1 || payload.Agents[0].Name != "a" || payload.Agents[0].Description != "d" {
 		t.Fatalf("unexpected agents: %#v", payload.Agents)
 	}
+	if payload.Agents[0].Source != "/agents" {
+		t.Fatalf("expected source to be reported, got %q", payload.Agents[0].Source)
+	}
 	if payload.Agents[0].Persona != nil {
 		t.Fatalf("persona should be omitted: %#v", payload.Agents[0])
 	}
@@ -97,3 +108,557 @@ func TestDelegateTaskHandlerPropagatesRunnerError(t *testing.T) {
 		t.Fatal("expected runner error")
 	}
 }
+
+type recordingRunner struct {
+	agent agents.Agent
+	task  string
+}
+
+func (r *recordingRunner) Run(ctx context.Context, agent agents.Agent, task string, workdir string, model string) (string, error) {
+	r.agent = agent
+	r.task = task
+	return "done", nil
+}
+
+func TestDelegateTaskHandlerUsesProjectAgents(t *testing.T) {
+	workdir := t.TempDir()
+	dir := filepath.Join(workdir, agents.ProjectAgentsDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.yaml"), []byte("persona: project\ndescription: project agent\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	repo := stubRepo{agents: []agents.Agent{{Name: "a", Persona: "global", Description: "d"}, {Name: "b", Persona: "p", Description: "d"}}}
+	runner := &recordingRunner{}
+	h := NewHandlers(repo, runner, zap.NewNop())
+
+	if _, err := h.DelegateTask(context.Background(), delegateArgs{Agent: "a", Task: "t", WorkingDirectory: workdir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if runner.agent.Persona != "project" {
+		t.Fatalf("expected project agent to override global, got %q", runner.agent.Persona)
+	}
+
+	result, err := h.ListAgents(context.Background(), listAgentsArgs{WorkingDirectory: workdir})
+	if err != nil {
+		t.Fatalf("ListAgents error: %v", err)
+	}
+	var payload struct {
+		Agents []agentSummary `json:"agents"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &payload); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	if len(payload.Agents) != 2 || payload.Agents[0].Description != "project agent" {
+		t.Fatalf("unexpected agents: %+v", payload.Agents)
+	}
+}
+
+func TestProjectAgentsCannotLiftAllowedRoots(t *testing.T) {
+	workdir := t.TempDir()
+	dir := filepath.Join(workdir, agents.ProjectAgentsDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "deploy.yaml"), []byte("persona: project\ndescription: shadow\npermissions: full\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "review.yaml"), []byte("persona: project\ndescription: shadow\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	repo := stubRepo{agents: []agents.Agent{
+		{Name: "deploy", Persona: "global", Description: "d", Permissions: agents.PermissionsReadOnly},
+		{Name: "review", Persona: "global", Description: "d", AllowedRoots: []string{"/srv/repos/*"}},
+	}}
+	runner := &recordingRunner{}
+	h := NewHandlers(repo, runner, zap.NewNop())
+
+	_, err := h.DelegateTask(context.Background(), delegateArgs{Agent: "review", Task: "t", WorkingDirectory: workdir})
+	if err == nil || !strings.Contains(err.Error(), "outside allowed_roots") {
+		t.Fatalf("expected the global allowed_roots to bind the project override, got %v", err)
+	}
+	_, err = h.DelegateTask(context.Background(), delegateArgs{Agent: "deploy", Task: "t", WorkingDirectory: workdir})
+	if err != nil || runner.agent.Persona != "global" {
+		t.Fatalf("expected the override changing permissions to be skipped, got %v, %+v", err, runner.agent)
+	}
+}
+
+func TestProjectAgentsExtendingGlobalAgentsAreBound(t *testing.T) {
+	workdir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("eval symlinks: %v", err)
+	}
+	dir := filepath.Join(workdir, agents.ProjectAgentsDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	files := map[string]string{
+		"leak.yaml":   "extends: deployer\npermissions: full\nallowed_roots: [\"/\"]\n",
+		"helper.yaml": "extends: deployer\npersona: project\nenv:\n  MODE: fast\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	repo := stubRepo{agents: []agents.Agent{{
+		Name: "deployer", Persona: "global", Description: "d",
+		Permissions:  agents.PermissionsReadOnly,
+		AllowedRoots: []string{workdir},
+		Env:          map[string]string{"TOKEN": "file:/run/secrets/token"},
+	}}}
+	runner := &recordingRunner{}
+	h := NewHandlers(repo, runner, zap.NewNop())
+
+	if _, err := h.DelegateTask(context.Background(), delegateArgs{Agent: "leak", Task: "t", WorkingDirectory: workdir}); err == nil {
+		t.Fatalf("expected the agent lifting permissions and allowed_roots to be skipped, ran %+v", runner.agent)
+	}
+	if _, err := h.DelegateTask(context.Background(), delegateArgs{Agent: "helper", Task: "t", WorkingDirectory: workdir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := runner.agent
+	if got.Permissions != agents.PermissionsReadOnly || len(got.AllowedRoots) != 1 || got.AllowedRoots[0] != workdir {
+		t.Fatalf("expected the base's permissions and allowed_roots, got %q %q", got.Permissions, got.AllowedRoots)
+	}
+	if _, ok := got.Env["TOKEN"]; ok || got.Env["MODE"] != "fast" {
+		t.Fatalf("expected only the project env, got %v", got.Env)
+	}
+}
+
+func TestDelegateTaskHandlerRendersPersonaTemplate(t *testing.T) {
+	repo := stubRepo{agents: []agents.Agent{{Name: "a", Persona: "Reviewing {{.WorkingDirectory}} for {{.Team}}", Description: "d", PersonaTemplate: true}}}
+	runner := &recordingRunner{}
+	h := NewHandlers(repo, runner, zap.NewNop())
+
+	workdir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("eval symlinks: %v", err)
+	}
+	if _, err := h.DelegateTask(context.Background(), delegateArgs{Agent: "a", Task: "t", WorkingDirectory: workdir}); err == nil {
+		t.Fatal("expected missing variable error")
+	}
+
+	args := delegateArgs{Agent: "a", Task: "t", WorkingDirectory: workdir, Variables: map[string]any{"Team": "platform"}}
+	if _, err := h.DelegateTask(context.Background(), args); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if runner.agent.Persona != "Reviewing "+workdir+" for platform" {
+		t.Fatalf("unexpected rendered persona: %q", runner.agent.Persona)
+	}
+}
+
+func TestDelegateTaskHandlerValidatesInputs(t *testing.T) {
+	inputs := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"base_ref": map[string]any{"type": "string"},
+			"paths":    map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+		"required": []any{"base_ref", "paths"},
+	}
+	repo := stubRepo{agents: []agents.Agent{{Name: "review", Persona: "Review against {{.Inputs.base_ref}}", Description: "d", PersonaTemplate: true, Inputs: inputs}}}
+	runner := &recordingRunner{}
+	h := NewHandlers(repo, runner, zap.NewNop())
+
+	_, err := h.DelegateTask(context.Background(), delegateArgs{Agent: "review", Task: "t", WorkingDirectory: "/tmp"})
+	if err == nil || !strings.Contains(err.Error(), "base_ref: is required") {
+		t.Fatalf("expected inputs validation error, got %v", err)
+	}
+
+	args := delegateArgs{Agent: "review", Task: "review it", WorkingDirectory: "/tmp", Inputs: map[string]any{"base_ref": "main", "paths": []any{"a.go"}}}
+	if _, err := h.DelegateTask(context.Background(), args); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(runner.task, "Inputs:") || !strings.Contains(runner.task, `"base_ref": "main"`) {
+		t.Fatalf("expected inputs rendered into task, got %q", runner.task)
+	}
+	if runner.agent.Persona != "Review against main" {
+		t.Fatalf("expected inputs available to persona template, got %q", runner.agent.Persona)
+	}
+
+	result, err := h.ListAgents(context.Background(), listAgentsArgs{})
+	if err != nil {
+		t.Fatalf("ListAgents error: %v", err)
+	}
+	if !strings.Contains(result.Content[0].Text, `"inputs":{`) {
+		t.Fatalf("expected inputs schema in list_agents, got %s", result.Content[0].Text)
+	}
+}
+
+func TestDelegateTaskHandlerReturnsStructuredContent(t *testing.T) {
+	outputSchema := map[string]any{"type": "object", "required": []any{"summary"}}
+	repo := stubRepo{agents: []agents.Agent{{Name: "a", Persona: "p", Description: "d", OutputSchema: outputSchema}}}
+	runner := stubRunner{output: "Result:\n```json\n{\"summary\": \"ok\"}\n```"}
+	h := NewHandlers(repo, runner, zap.NewNop())
+
+	result, err := h.DelegateTask(context.Background(), delegateArgs{Agent: "a", Task: "t", WorkingDirectory: "/tmp"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	structured, ok := result.StructuredContent.(map[string]any)
+	if !ok || structured["summary"] != "ok" {
+		t.Fatalf("unexpected structured content: %#v", result.StructuredContent)
+	}
+	if result.Content[0].Text != `{"summary":"ok"}` {
+		t.Fatalf("expected JSON text content, got %q", result.Content[0].Text)
+	}
+}
+
+func TestDelegateTaskHandlerAppliesTimeoutOverride(t *testing.T) {
+	repo := stubRepo{agents: []agents.Agent{{Name: "a", Persona: "p", Description: "d", Timeout: time.Minute}}}
+	runner := &recordingRunner{}
+	h := NewHandlers(repo, runner, zap.NewNop())
+
+	if _, err := h.DelegateTask(context.Background(), delegateArgs{Agent: "a", Task: "t", WorkingDirectory: "/tmp", TimeoutSeconds: -1}); err == nil {
+		t.Fatal("expected error for negative timeout")
+	}
+
+	if _, err := h.DelegateTask(context.Background(), delegateArgs{Agent: "a", Task: "t", WorkingDirectory: "/tmp"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if runner.agent.Timeout != time.Minute {
+		t.Fatalf("expected agent timeout to be kept, got %s", runner.agent.Timeout)
+	}
+
+	if _, err := h.DelegateTask(context.Background(), delegateArgs{Agent: "a", Task: "t", WorkingDirectory: "/tmp", TimeoutSeconds: 5}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if runner.agent.Timeout != 5*time.Second {
+		t.Fatalf("expected per-call timeout override, got %s", runner.agent.Timeout)
+	}
+}
+
+func TestListAgentsHandlerReportsToolPolicy(t *testing.T) {
+	repo := stubRepo{agents: []agents.Agent{{Name: "a", Persona: "p", Description: "d", Permissions: "read-only", AllowedTools: []string{"shell(git:*)"}, DeniedTools: []string{"write"}}}}
+	h := NewHandlers(repo, stubRunner{}, zap.NewNop())
+
+	result, err := h.ListAgents(context.Background(), listAgentsArgs{})
+	if err != nil {
+		t.Fatalf("ListAgents error: %v", err)
+	}
+	expected := `"permissions":"read-only","tools":{"allowed":["shell(git:*)"],"denied":["write"]}`
+	if !strings.Contains(result.Content[0].Text, expected) {
+		t.Fatalf("expected %s in %s", expected, result.Content[0].Text)
+	}
+}
+
+func TestListAgentsHandlerFiltersAndRanks(t *testing.T) {
+	repo := stubRepo{agents: []agents.Agent{
+		{Name: "writer", Persona: "p", Description: "Writes docs", Tags: []string{"docs"}, Category: "writing"},
+		{Name: "docs-fetcher", Persona: "p", Description: "Fetches", Tags: []string{"research"}, Category: "research"},
+		{Name: "reviewer", Persona: "p", Description: "Reviews code", Category: "quality"},
+	}}
+	h := NewHandlers(repo, stubRunner{}, zap.NewNop())
+
+	result, err := h.ListAgents(context.Background(), listAgentsArgs{Query: "docs"})
+	if err != nil {
+		t.Fatalf("ListAgents error: %v", err)
+	}
+	var payload struct {
+		Agents []struct {
+			Name     string   `json:"name"`
+			Category string   `json:"category"`
+			Tags     []string `json:"tags"`
+		} `json:"agents"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &payload); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	if len(payload.Agents) != 2 || payload.Agents[0].Name != "docs-fetcher" || payload.Agents[1].Name != "writer" {
+		t.Fatalf("unexpected ranking: %#v", payload.Agents)
+	}
+	if payload.Agents[1].Category != "writing" || len(payload.Agents[1].Tags) != 1 {
+		t.Fatalf("expected tags and category in summary: %#v", payload.Agents[1])
+	}
+
+	result, err = h.ListAgents(context.Background(), listAgentsArgs{Category: "QUALITY"})
+	if err != nil {
+		t.Fatalf("ListAgents error: %v", err)
+	}
+	if !strings.Contains(result.Content[0].Text, `"reviewer"`) || strings.Contains(result.Content[0].Text, `"writer"`) {
+		t.Fatalf("expected category filter, got %s", result.Content[0].Text)
+	}
+}
+
+func TestDelegateTaskHandlerEnforcesAllowedRoots(t *testing.T) {
+	allowed, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("eval symlinks: %v", err)
+	}
+	outside := t.TempDir()
+	repo := stubRepo{agents: []agents.Agent{
+		{Name: "free", Persona: "p", Description: "d"},
+		{Name: "scoped", Persona: "p", Description: "d", AllowedRoots: []string{filepath.Join(allowed, "repos", "*")}},
+	}}
+
+	h := NewHandlers(repo, stubRunner{output: "done"}, zap.NewNop(), WithAllowedRoots([]string{allowed}))
+	_, err = h.DelegateTask(context.Background(), delegateArgs{Agent: "free", Task: "t", WorkingDirectory: outside})
+	if err == nil || !strings.Contains(err.Error(), "--allowed-root") {
+		t.Fatalf("expected server root rejection, got %v", err)
+	}
+	if _, err := h.DelegateTask(context.Background(), delegateArgs{Agent: "free", Task: "t", WorkingDirectory: allowed}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = h.DelegateTask(context.Background(), delegateArgs{Agent: "scoped", Task: "t", WorkingDirectory: allowed})
+	if err == nil || !strings.Contains(err.Error(), `allowed_roots`) || !strings.Contains(err.Error(), `"scoped"`) {
+		t.Fatalf("expected agent root rejection, got %v", err)
+	}
+	repoDir := filepath.Join(allowed, "repos", "app")
+	if err := os.MkdirAll(repoDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if _, err := h.DelegateTask(context.Background(), delegateArgs{Agent: "scoped", Task: "t", WorkingDirectory: repoDir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+type planningRunner struct {
+	stubRunner
+}
+
+func (planningRunner) Plan(agent agents.Agent, model string) ([]runner.Route, error) {
+	return []runner.Route{{Runner: "copilot", Model: model}, {Runner: "codex", Model: model}}, nil
+}
+
+func TestDescribeAgentHandler(t *testing.T) {
+	repo := stubRepo{agents: []agents.Agent{{Name: "a", Persona: "secret persona", Description: "d", Model: "gpt-5", Tags: []string{"docs"}}}}
+
+	h := NewHandlers(repo, planningRunner{}, zap.NewNop())
+	if _, err := h.DescribeAgent(context.Background(), describeAgentArgs{Agent: "missing"}); err == nil {
+		t.Fatal("expected error for unknown agent")
+	}
+	result, err := h.DescribeAgent(context.Background(), describeAgentArgs{Agent: "a"})
+	if err != nil {
+		t.Fatalf("DescribeAgent error: %v", err)
+	}
+	text := result.Content[0].Text
+	for _, want := range []string{`"model":"gpt-5"`, `"tags":["docs"]`, `"routing":[{"runner":"copilot","model":"gpt-5"},{"runner":"codex","model":"gpt-5"}]`} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %s in %s", want, text)
+		}
+	}
+	if strings.Contains(text, "secret persona") {
+		t.Fatalf("persona should be hidden by default: %s", text)
+	}
+
+	h = NewHandlers(repo, stubRunner{}, zap.NewNop(), WithPersonaDisclosure(true))
+	result, err = h.DescribeAgent(context.Background(), describeAgentArgs{Agent: "a"})
+	if err != nil {
+		t.Fatalf("DescribeAgent error: %v", err)
+	}
+	if !strings.Contains(result.Content[0].Text, `"persona":"secret persona"`) || strings.Contains(result.Content[0].Text, "routing") {
+		t.Fatalf("expected persona and no routing, got %s", result.Content[0].Text)
+	}
+}
+
+func TestAgentManagementHandlers(t *testing.T) {
+	dir := t.TempDir()
+	h := NewHandlers(agents.NewYAMLRepository(dir), stubRunner{}, zap.NewNop(), WithAgentStore(agents.NewStore(dir)))
+	ctx := context.Background()
+
+	result, err := h.CreateAgent(ctx, writeAgentArgs{Name: "drafter", Definition: map[string]any{
+		"persona":     "You draft.\nKeep it short.",
+		"description": "Drafts things",
+		"tags":        []any{"docs"},
+	}})
+	if err != nil {
+		t.Fatalf("CreateAgent error: %v", err)
+	}
+	var created agentWriteResult
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &created); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	listed, err := h.ListAgents(ctx, listAgentsArgs{})
+	if err != nil || !strings.Contains(listed.Content[0].Text, `"drafter"`) {
+		t.Fatalf("expected created agent to be listed: %v %v", listed, err)
+	}
+	if !strings.Contains(listed.Content[0].Text, `"file_hash":"`+created.Hash+`"`) {
+		t.Fatalf("expected list_agents to report the file hash, got %s", listed.Content[0].Text)
+	}
+	described, err := h.DescribeAgent(ctx, describeAgentArgs{Agent: "drafter"})
+	if err != nil || !strings.Contains(described.Content[0].Text, `"file_hash":"`+created.Hash+`"`) {
+		t.Fatalf("expected describe_agent to report the file hash: %v %v", described, err)
+	}
+
+	if _, err := h.UpdateAgent(ctx, writeAgentArgs{Name: "drafter", Definition: map[string]any{"persona": "p", "description": "d"}, ExpectedHash: "sha256:old"}); !errors.Is(err, agents.ErrHashMismatch) {
+		t.Fatalf("expected hash mismatch, got %v", err)
+	}
+	if _, err := h.UpdateAgent(ctx, writeAgentArgs{Name: "drafter", Definition: map[string]any{"persona": "p"}, ExpectedHash: created.Hash}); err == nil {
+		t.Fatal("expected validation error for missing description")
+	}
+	if _, err := h.DeleteAgent(ctx, deleteAgentArgs{Name: "drafter", ExpectedHash: created.Hash}); err != nil {
+		t.Fatalf("DeleteAgent error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "drafter.yaml")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected file removed, got %v", err)
+	}
+
+	disabled := NewHandlers(stubRepo{}, stubRunner{}, zap.NewNop())
+	if _, err := disabled.CreateAgent(ctx, writeAgentArgs{Name: "x", Definition: map[string]any{"persona": "p"}}); err == nil {
+		t.Fatal("expected error when management is disabled")
+	}
+}
+
+func TestLogDiagnosticsOncePerFileProblem(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	h := NewHandlers(stubRepo{}, stubRunner{}, zap.New(core))
+
+	broken := []agents.Diagnostic{{File: "/a/x.yaml", Severity: agents.SeverityError, Message: "bad"}}
+	h.logDiagnostics(broken)
+	h.logDiagnostics(broken)
+	if logs.Len() != 1 {
+		t.Fatalf("expected one log for a repeated problem, got %d", logs.Len())
+	}
+	h.logDiagnostics([]agents.Diagnostic{{File: "/a/x.yaml", Severity: agents.SeverityError, Message: "worse"}})
+	if logs.Len() != 2 {
+		t.Fatalf("expected a changed problem to be logged, got %d", logs.Len())
+	}
+
+	for i := 0; i < maxLoggedFiles+10; i++ {
+		h.logDiagnostics([]agents.Diagnostic{{File: fmt.Sprintf("/p%d/x.yaml", i), Message: "bad"}})
+	}
+	if len(h.logged) > maxLoggedFiles {
+		t.Fatalf("expected at most %d tracked files, got %d", maxLoggedFiles, len(h.logged))
+	}
+}
+
+func TestCatalogStatusHandler(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "good.yaml"), []byte("persona: p\ndescription: d\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("persona: [\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	h := NewHandlers(agents.NewYAMLRepository(dir), stubRunner{output: "done"}, zap.NewNop())
+
+	if _, err := h.DelegateTask(context.Background(), delegateArgs{Agent: "good", Task: "t", WorkingDirectory: "/tmp"}); err != nil {
+		t.Fatalf("healthy agent should still be served: %v", err)
+	}
+	result, err := h.CatalogStatus(context.Background(), catalogStatusArgs{})
+	if err != nil {
+		t.Fatalf("CatalogStatus error: %v", err)
+	}
+	var status catalogStatus
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &status); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if status.Agents != 1 || len(status.Diagnostics) != 1 || status.Diagnostics[0].File != filepath.Join(dir, "broken.yaml") {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+}
+
+func TestDelegateTaskHandlerResolvesAliasesAndDeprecations(t *testing.T) {
+	repo := stubRepo{agents: []agents.Agent{
+		{Name: "reviewer", Persona: "p", Description: "d", Aliases: []string{"code-reviewer"}},
+		{Name: "old-reviewer", Persona: "old", Description: "d", Deprecated: &agents.Deprecation{ReplacedBy: "reviewer", Message: "renamed"}},
+	}}
+	runner := &recordingRunner{}
+	h := NewHandlers(repo, runner, zap.NewNop())
+
+	result, err := h.DelegateTask(context.Background(), delegateArgs{Agent: "old-reviewer", Task: "t", WorkingDirectory: "/tmp"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if runner.agent.Name != "reviewer" {
+		t.Fatalf("expected forward to reviewer, got %s", runner.agent.Name)
+	}
+	if result.Meta == nil || result.Meta.Agent != "reviewer" || len(result.Meta.Warnings) != 1 || !strings.Contains(result.Meta.Warnings[0], "renamed") {
+		t.Fatalf("expected deprecation warning in meta, got %#v", result.Meta)
+	}
+
+	if _, err := h.DelegateTask(context.Background(), delegateArgs{Agent: "code-reviewer", Task: "t", WorkingDirectory: "/tmp"}); err != nil {
+		t.Fatalf("alias should resolve: %v", err)
+	}
+	if runner.agent.Name != "reviewer" {
+		t.Fatalf("expected alias to resolve to reviewer, got %s", runner.agent.Name)
+	}
+
+	listed, err := h.ListAgents(context.Background(), listAgentsArgs{})
+	if err != nil {
+		t.Fatalf("ListAgents error: %v", err)
+	}
+	if strings.Contains(listed.Content[0].Text, "old-reviewer") {
+		t.Fatalf("deprecated agent should be hidden: %s", listed.Content[0].Text)
+	}
+	listed, err = h.ListAgents(context.Background(), listAgentsArgs{IncludeDeprecated: true})
+	if err != nil {
+		t.Fatalf("ListAgents error: %v", err)
+	}
+	if !strings.Contains(listed.Content[0].Text, `"deprecated":{"replaced_by":"reviewer","message":"renamed"}`) {
+		t.Fatalf("expected deprecated agent when requested: %s", listed.Content[0].Text)
+	}
+}
+
+func TestDelegateTaskHandlerPinsAgentVersion(t *testing.T) {
+	repo := stubRepo{agents: []agents.Agent{{Name: "a", Persona: "p", Description: "d", Version: "2"}}}
+	h := NewHandlers(repo, &recordingRunner{}, zap.NewNop())
+
+	result, err := h.DelegateTask(context.Background(), delegateArgs{Agent: "a", Task: "t", WorkingDirectory: "/tmp", AgentVersion: "2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	hash := result.Meta.DefinitionHash
+	if result.Meta.AgentVersion != "2" || !strings.HasPrefix(hash, "sha256:") {
+		t.Fatalf("expected version and hash in meta, got %#v", result.Meta)
+	}
+	if _, err := h.DelegateTask(context.Background(), delegateArgs{Agent: "a", Task: "t", WorkingDirectory: "/tmp", AgentVersion: hash}); err != nil {
+		t.Fatalf("pinning the content hash should succeed: %v", err)
+	}
+
+	for _, pinned := range []string{"1", "sha256:stale"} {
+		_, err := h.DelegateTask(context.Background(), delegateArgs{Agent: "a", Task: "t", WorkingDirectory: "/tmp", AgentVersion: pinned})
+		if err == nil || !strings.Contains(err.Error(), "changed") {
+			t.Fatalf("expected pin %q to fail, got %v", pinned, err)
+		}
+	}
+
+	listed, err := h.ListAgents(context.Background(), listAgentsArgs{})
+	if err != nil {
+		t.Fatalf("ListAgents error: %v", err)
+	}
+	if !strings.Contains(listed.Content[0].Text, `"version":"2","definition_hash":"`+hash+`"`) {
+		t.Fatalf("expected version and hash in list: %s", listed.Content[0].Text)
+	}
+}
+
+func TestRequirementsFilterAgents(t *testing.T) {
+	workdir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workdir, "go.mod"), []byte("module x\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	repo := stubRepo{agents: []agents.Agent{
+		{Name: "gopher", Persona: "p", Description: "d", Requires: &agents.Requirements{Files: []string{"go.mod"}}},
+		{Name: "node", Persona: "p", Description: "d", Requires: &agents.Requirements{Files: []string{"package.json"}}},
+	}}
+	h := NewHandlers(repo, stubRunner{output: "done"}, zap.NewNop())
+	ctx := context.Background()
+
+	listed, err := h.ListAgents(ctx, listAgentsArgs{WorkingDirectory: workdir})
+	if err != nil {
+		t.Fatalf("ListAgents error: %v", err)
+	}
+	if !strings.Contains(listed.Content[0].Text, `"gopher"`) || strings.Contains(listed.Content[0].Text, `"node"`) {
+		t.Fatalf("expected node to be filtered: %s", listed.Content[0].Text)
+	}
+	listed, err = h.ListAgents(ctx, listAgentsArgs{WorkingDirectory: workdir, Verbose: true})
+	if err != nil {
+		t.Fatalf("ListAgents error: %v", err)
+	}
+	if !strings.Contains(listed.Content[0].Text, `"unavailable":"package.json not found in the working directory"`) {
+		t.Fatalf("expected reason in verbose mode: %s", listed.Content[0].Text)
+	}
+
+	_, err = h.DelegateTask(ctx, delegateArgs{Agent: "node", Task: "t", WorkingDirectory: workdir})
+	if err == nil || !strings.Contains(err.Error(), `agent "node" is unavailable: package.json not found`) {
+		t.Fatalf("expected unavailable error, got %v", err)
+	}
+	if _, err := h.DelegateTask(ctx, delegateArgs{Agent: "gopher", Task: "t", WorkingDirectory: workdir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
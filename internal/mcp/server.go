@@ -20,10 +20,10 @@ type Server struct {
 	handlers *Handlers
 }
 
-func NewServer(logger *zap.Logger, repo agents.Repository, r runner.AgentRunner) *Server {
+func NewServer(logger *zap.Logger, repo agents.Repository, r runner.AgentRunner, opts ...Option) *Server {
 	return &Server{
 		logger:   logger,
-		handlers: NewHandlers(repo, r, logger),
+		handlers: NewHandlers(repo, r, logger, opts...),
 	}
 }
 
@@ -92,11 +92,41 @@ func (s *Server) listTools(id any) Response {
 	tools := []Tool{
 		{
 			Name:        "list_agents",
-			Description: "List all available agents with name and description.",
+			Description: "List all available agents with name and description; optionally search by text, tags or category.",
 			InputSchema: map[string]any{
-				"type":       "object",
-				"properties": map[string]any{},
-				"required":   []string{},
+				"type": "object",
+				"properties": map[string]any{
+					"working_directory":  map[string]any{"type": "string", "description": "Optional absolute workspace path; includes project agents from .subagents/agents"},
+					"query":              map[string]any{"type": "string", "description": "Case-insensitive search over name, aliases, description and tags; results are ranked"},
+					"tags":               map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "Only agents carrying all of these tags"},
+					"category":           map[string]any{"type": "string", "description": "Only agents in this category"},
+					"include_deprecated": map[string]any{"type": "boolean", "description": "Also list deprecated agents (hidden by default)"},
+					"verbose":            map[string]any{"type": "boolean", "description": "Also list agents whose requires conditions are unmet, with the reason"},
+				},
+				"required": []string{},
+			},
+		},
+		{
+			Name:        "catalog_status",
+			Description: "Report how many agents loaded and which agent files were skipped, with per-file diagnostics.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"working_directory": map[string]any{"type": "string", "description": "Optional absolute workspace path; includes project agents from .subagents/agents"},
+				},
+				"required": []string{},
+			},
+		},
+		{
+			Name:        "describe_agent",
+			Description: "Describe an agent: model, inputs, tags and the ordered runners delegation would try.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"agent":             map[string]any{"type": "string", "description": "Agent name to describe"},
+					"working_directory": map[string]any{"type": "string", "description": "Optional absolute workspace path; includes project agents from .subagents/agents"},
+				},
+				"required": []string{"agent"},
 			},
 		},
 		{
@@ -105,14 +135,21 @@ func (s *Server) listTools(id any) Response {
 			InputSchema: map[string]any{
 				"type": "object",
 				"properties": map[string]any{
-					"agent":             map[string]any{"type": "string", "description": "Agent name to delegate to"},
+					"agent":             map[string]any{"type": "string", "description": "Agent name or alias to delegate to"},
 					"task":              map[string]any{"type": "string", "description": "Task to be executed"},
 					"working_directory": map[string]any{"type": "string", "description": "Absolute workspace path for execution"},
+					"variables":         map[string]any{"type": "object", "description": "Optional values for agents with persona_template: true, referenced as {{.name}}; built-in variables cannot be overridden"},
+					"inputs":            map[string]any{"type": "object", "description": "Structured arguments validated against the agent's inputs schema (see list_agents)"},
+					"timeout_seconds":   map[string]any{"type": "integer", "description": "Optional per-attempt timeout overriding the agent and server defaults"},
+					"agent_version":     map[string]any{"type": "string", "description": "Optional agent version or definition_hash (see list_agents); the call fails if the agent no longer matches"},
 				},
 				"required": []string{"agent", "task", "working_directory"},
 			},
 		},
 	}
+	if s.handlers.store != nil {
+		tools = append(tools, managementTools()...)
+	}
 	return Response{
 		JSONRPC: "2.0",
 		ID:      id,
@@ -120,6 +157,51 @@ func (s *Server) listTools(id any) Response {
 	}
 }
 
+// managementTools are listed only when agent writes are enabled.
+func managementTools() []Tool {
+	definition := map[string]any{"type": "object", "description": "Agent definition using the YAML file fields (persona, description, model, ...)"}
+	expectedHash := map[string]any{"type": "string", "description": "Hash returned by the previous create_agent or update_agent call"}
+	return []Tool{
+		{
+			Name:        "create_agent",
+			Description: "Create a new agent definition in the writable agents directory.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name":       map[string]any{"type": "string", "description": "Agent name; becomes <name>.yaml"},
+					"definition": definition,
+				},
+				"required": []string{"name", "definition"},
+			},
+		},
+		{
+			Name:        "update_agent",
+			Description: "Replace an agent definition if it has not changed since expected_hash.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name":          map[string]any{"type": "string", "description": "Agent name"},
+					"definition":    definition,
+					"expected_hash": expectedHash,
+				},
+				"required": []string{"name", "definition", "expected_hash"},
+			},
+		},
+		{
+			Name:        "delete_agent",
+			Description: "Delete an agent definition if it has not changed since expected_hash.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name":          map[string]any{"type": "string", "description": "Agent name"},
+					"expected_hash": expectedHash,
+				},
+				"required": []string{"name", "expected_hash"},
+			},
+		},
+	}
+}
+
 func (s *Server) callTool(ctx context.Context, req Request) Response {
 	var params ToolsCallParams
 	if err := json.Unmarshal(req.Params, &params); err != nil {
@@ -128,12 +210,38 @@ func (s *Server) callTool(ctx context.Context, req Request) Response {
 
 	switch params.Name {
 	case "list_agents":
-		result, err := s.handlers.ListAgents(ctx)
+		args, err := decodeOptionalArgs[listAgentsArgs](params.Arguments)
+		if err != nil {
+			return errorResponse(req.ID, ErrCodeInvalidParams, "invalid list_agents arguments")
+		}
+		result, err := s.handlers.ListAgents(ctx, args)
 		if err != nil {
 			s.logger.Error("list_agents failed", zap.Error(err))
 			return errorResponse(req.ID, ErrCodeInternal, err.Error())
 		}
 		return Response{JSONRPC: "2.0", ID: req.ID, Result: result}
+	case "catalog_status":
+		args, err := decodeOptionalArgs[catalogStatusArgs](params.Arguments)
+		if err != nil {
+			return errorResponse(req.ID, ErrCodeInvalidParams, "invalid catalog_status arguments")
+		}
+		result, err := s.handlers.CatalogStatus(ctx, args)
+		if err != nil {
+			s.logger.Error("catalog_status failed", zap.Error(err))
+			return errorResponse(req.ID, ErrCodeInternal, err.Error())
+		}
+		return Response{JSONRPC: "2.0", ID: req.ID, Result: result}
+	case "describe_agent":
+		args, err := decodeArgs[describeAgentArgs](params.Arguments)
+		if err != nil {
+			return errorResponse(req.ID, ErrCodeInvalidParams, "invalid describe_agent arguments")
+		}
+		result, err := s.handlers.DescribeAgent(ctx, args)
+		if err != nil {
+			s.logger.Error("describe_agent failed", zap.Error(err))
+			return errorResponse(req.ID, ErrCodeInternal, err.Error())
+		}
+		return Response{JSONRPC: "2.0", ID: req.ID, Result: result}
 	case "delegate_task":
 		args, err := decodeArgs[delegateArgs](params.Arguments)
 		if err != nil {
@@ -145,11 +253,46 @@ func (s *Server) callTool(ctx context.Context, req Request) Response {
 			return errorResponse(req.ID, ErrCodeInternal, err.Error())
 		}
 		return Response{JSONRPC: "2.0", ID: req.ID, Result: result}
+	case "create_agent", "update_agent", "delete_agent":
+		if s.handlers.store == nil {
+			return errorResponse(req.ID, ErrCodeMethodNotFound, "tool not found")
+		}
+		return s.callManagementTool(ctx, req.ID, params)
 	default:
 		return errorResponse(req.ID, ErrCodeMethodNotFound, "tool not found")
 	}
 }
 
+func (s *Server) callManagementTool(ctx context.Context, id any, params ToolsCallParams) Response {
+	var (
+		result textResult
+		err    error
+	)
+	switch params.Name {
+	case "delete_agent":
+		args, decodeErr := decodeArgs[deleteAgentArgs](params.Arguments)
+		if decodeErr != nil {
+			return errorResponse(id, ErrCodeInvalidParams, "invalid delete_agent arguments")
+		}
+		result, err = s.handlers.DeleteAgent(ctx, args)
+	default:
+		args, decodeErr := decodeArgs[writeAgentArgs](params.Arguments)
+		if decodeErr != nil {
+			return errorResponse(id, ErrCodeInvalidParams, "invalid "+params.Name+" arguments")
+		}
+		if params.Name == "create_agent" {
+			result, err = s.handlers.CreateAgent(ctx, args)
+		} else {
+			result, err = s.handlers.UpdateAgent(ctx, args)
+		}
+	}
+	if err != nil {
+		s.logger.Error(params.Name+" failed", zap.Error(err))
+		return errorResponse(id, ErrCodeInternal, err.Error())
+	}
+	return Response{JSONRPC: "2.0", ID: id, Result: result}
+}
+
 // NewlineDelimitedCodec ensures JSON-RPC messages remain line separated for stdio transports.
 func NewlineDelimitedCodec(enc *json.Encoder) *json.Encoder {
 	enc.SetEscapeHTML(false)
@@ -48,3 +48,31 @@ func TestNotificationsInitializedIgnored(t *testing.T) {
 		t.Fatalf("expected notification to be ignored")
 	}
 }
+
+func TestManagementToolsRequireStore(t *testing.T) {
+	toolNames := func(s *Server) []string {
+		resp := s.listTools(1)
+		var names []string
+		for _, tool := range resp.Result.(ToolsListResult).Tools {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+
+	s := NewServer(zap.NewNop(), initStubRepo{}, initStubRunner{})
+	for _, name := range toolNames(s) {
+		if name == "create_agent" {
+			t.Fatal("management tools should be hidden by default")
+		}
+	}
+	resp := s.callTool(context.Background(), Request{JSONRPC: "2.0", ID: 1, Params: []byte(`{"name":"create_agent","arguments":{}}`)})
+	if resp.Error == nil || resp.Error.Code != ErrCodeMethodNotFound {
+		t.Fatalf("expected method not found, got %#v", resp)
+	}
+
+	s = NewServer(zap.NewNop(), initStubRepo{}, initStubRunner{}, WithAgentStore(agents.NewStore(t.TempDir())))
+	names := toolNames(s)
+	if len(names) != 7 || names[4] != "create_agent" || names[6] != "delete_agent" {
+		t.Fatalf("unexpected tools: %v", names)
+	}
+}
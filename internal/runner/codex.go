@@ -30,6 +30,18 @@ func NewCodexRunner(logger *zap.Logger, supportedModels []string) *CodexRunner {
 	}
 }
 
+// supports reports whether codex can honor the agent's permissions and tool
+// lists.
+func (c *CodexRunner) supports(agent agents.Agent) error {
+	if _, err := codexSandbox(agent.Permissions); err != nil {
+		return err
+	}
+	if len(agent.AllowedTools) > 0 || len(agent.DeniedTools) > 0 {
+		return &ErrUnsupported{RunnerName: "codex", Reason: "tool allow/deny lists not supported"}
+	}
+	return nil
+}
+
 func (c *CodexRunner) Run(ctx context.Context, agent agents.Agent, task string, workdir string, model string) (string, error) {
 	if task == "" {
 		return "", errors.New("task is required")
@@ -43,11 +55,27 @@ func (c *CodexRunner) Run(ctx context.Context, agent agents.Agent, task string,
 		return "", fmt.Errorf("model %q not supported by codex runner", model)
 	}
 
-	prompt := buildAgentPrompt(agent, task)
+	if err := c.supports(agent); err != nil {
+		return "", err
+	}
+	sandbox, err := codexSandbox(agent.Permissions)
+	if err != nil {
+		return "", err
+	}
+
+	env, err := agentEnv(agent)
+	if err != nil {
+		return "", err
+	}
+
+	prompt, err := buildAgentPrompt(ctx, c.logger, agent, task, resolvedWorkdir)
+	if err != nil {
+		return "", err
+	}
 
 	args := []string{
 		"--cd", resolvedWorkdir,
-		"--sandbox", "read-only",
+		"--sandbox", sandbox,
 		"--ask-for-approval", "never",
 		"exec",
 		"--skip-git-repo-check",
@@ -58,6 +86,8 @@ func (c *CodexRunner) Run(ctx context.Context, agent agents.Agent, task string,
 	args = append(args, prompt)
 
 	cmd := c.execCommand(ctx, "codex", args...)
+	configureProcess(cmd)
+	cmd.Env = env
 	cmd.Dir = resolvedWorkdir
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -72,12 +102,16 @@ func (c *CodexRunner) Run(ctx context.Context, agent agents.Agent, task string,
 		zap.String("workdir", resolvedWorkdir),
 		zap.String("task", truncate(task, 200)),
 		zap.String("model", model),
+		zap.Strings("env_keys", envKeys(agent)),
 		zap.Duration("duration", duration),
 		zap.ByteString("stderr", stderr.Bytes()),
 		zap.Error(err),
 	)
 
 	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", &ErrTimeout{RunnerName: "codex", Partial: strings.TrimSpace(stdout.String())}
+		}
 		combined := stderr.String() + stdout.String()
 		if isCodexUsageLimitMessage(combined) {
 			return "", &ErrUsageLimitExceeded{
@@ -91,6 +125,20 @@ func (c *CodexRunner) Run(ctx context.Context, agent agents.Agent, task string,
 	return strings.TrimSpace(stdout.String()), nil
 }
 
+// codexSandbox maps a permission profile onto a Codex sandbox mode.
+func codexSandbox(profile string) (string, error) {
+	switch profile {
+	case "", agents.PermissionsReadOnly:
+		return "read-only", nil
+	case agents.PermissionsWorkspaceWrite:
+		return "workspace-write", nil
+	case agents.PermissionsFull:
+		return "danger-full-access", nil
+	default:
+		return "", &ErrUnsupported{RunnerName: "codex", Reason: fmt.Sprintf("permissions %q not supported", profile)}
+	}
+}
+
 func truncate(s string, limit int) string {
 	if len(s) <= limit {
 		return s
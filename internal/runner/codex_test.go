@@ -6,6 +6,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -143,3 +144,60 @@ func TestCodexRunner_OtherErrorNotUsageLimit(t *testing.T) {
 		t.Fatalf("expected generic error, not ErrUsageLimitExceeded: %v", err)
 	}
 }
+
+func TestCodexRunner_TimeoutReturnsPartialOutput(t *testing.T) {
+	logger := zap.NewNop()
+	r := NewCodexRunner(logger, nil)
+
+	dir := t.TempDir()
+	r.execCommand = func(ctx context.Context, name string, arg ...string) *exec.Cmd {
+		// The background child inherits stdout, so killing only the shell
+		// would leave Run waiting on the pipe.
+		return exec.CommandContext(ctx, "sh", "-c", "sleep 30 & echo partial; wait")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := r.Run(ctx, agents.Agent{Name: "agent", Persona: "p", Description: "d"}, "do something", dir, "")
+	if elapsed := time.Since(start); elapsed >= processWaitDelay {
+		t.Fatalf("Run blocked for %s after the timeout", elapsed)
+	}
+	var timeoutErr *ErrTimeout
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected ErrTimeout, got: %v", err)
+	}
+	if timeoutErr.RunnerName != "codex" || timeoutErr.Partial != "partial" {
+		t.Fatalf("unexpected timeout error: %+v", timeoutErr)
+	}
+}
+
+func TestCodexSandboxProfiles(t *testing.T) {
+	cases := map[string]string{
+		"":                               "read-only",
+		agents.PermissionsReadOnly:       "read-only",
+		agents.PermissionsWorkspaceWrite: "workspace-write",
+		agents.PermissionsFull:           "danger-full-access",
+	}
+	for profile, want := range cases {
+		got, err := codexSandbox(profile)
+		if err != nil {
+			t.Fatalf("profile %q: unexpected error: %v", profile, err)
+		}
+		if got != want {
+			t.Fatalf("profile %q: expected %s, got %s", profile, want, got)
+		}
+	}
+	if _, err := codexSandbox("admin"); !IsUnsupportedError(err) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestCodexRunnerRejectsToolLists(t *testing.T) {
+	r := NewCodexRunner(zap.NewNop(), nil)
+	agent := agents.Agent{Name: "agent", Persona: "p", Description: "d", DeniedTools: []string{"shell(rm)"}}
+	if _, err := r.Run(context.Background(), agent, "task", t.TempDir(), ""); !IsUnsupportedError(err) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+}
@@ -4,13 +4,26 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"subagents-mcp/internal/agents"
 )
 
 // Config describes available runners, their priorities, and supported models.
 type Config struct {
 	Runners []RunnerConfig `yaml:"runners"`
+	// DefaultTimeout bounds each runner attempt for agents without their own
+	// timeout. Zero disables the limit.
+	DefaultTimeout time.Duration `yaml:"default_timeout"`
+	// MaxExamples caps the agent examples included in prompts for agents
+	// without their own max_examples. Zero means agents.DefaultMaxExamples
+	// and agents.NoExamples (-1) leaves examples out.
+	MaxExamples int `yaml:"max_examples"`
+	// PromptTemplate lays out prompts for agents without their own
+	// prompt_template. Empty keeps the built-in layout.
+	PromptTemplate string `yaml:"prompt_template"`
 }
 
 // RunnerConfig represents a single runner entry loaded from YAML.
@@ -44,6 +57,18 @@ func LoadConfig(path string) (Config, error) {
 }
 
 func (c *Config) validateAndNormalize() error {
+	if c.DefaultTimeout < 0 {
+		return fmt.Errorf("default_timeout must not be negative")
+	}
+	if c.MaxExamples < agents.NoExamples {
+		return fmt.Errorf("max_examples must be -1 (none) or more")
+	}
+	c.PromptTemplate = strings.TrimSpace(c.PromptTemplate)
+	if c.PromptTemplate != "" {
+		if err := agents.CheckPromptTemplate(c.PromptTemplate); err != nil {
+			return fmt.Errorf("prompt_template: %w", err)
+		}
+	}
 	for i := range c.Runners {
 		c.Runners[i].Name = strings.TrimSpace(c.Runners[i].Name)
 		if c.Runners[i].Name == "" {
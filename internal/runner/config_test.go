@@ -4,6 +4,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"subagents-mcp/internal/agents"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -38,6 +41,63 @@ runners:
 		}
 	})
 
+	t.Run("parses default timeout", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "config.yaml")
+		writeFile(t, path, "default_timeout: 90s\nrunners:\n  - name: codex\n    priority: 1\n")
+
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			t.Fatalf("LoadConfig error: %v", err)
+		}
+		if cfg.DefaultTimeout != 90*time.Second {
+			t.Fatalf("expected 90s default timeout, got %s", cfg.DefaultTimeout)
+		}
+	})
+
+	t.Run("parses max examples", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "config.yaml")
+		writeFile(t, path, "max_examples: 5\n")
+
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			t.Fatalf("LoadConfig error: %v", err)
+		}
+		if cfg.MaxExamples != 5 {
+			t.Fatalf("expected max_examples 5, got %d", cfg.MaxExamples)
+		}
+
+		writeFile(t, path, "max_examples: -1\n")
+		if cfg, err := LoadConfig(path); err != nil || cfg.MaxExamples != agents.NoExamples {
+			t.Fatalf("expected max_examples -1 to disable examples, got %d, %v", cfg.MaxExamples, err)
+		}
+
+		writeFile(t, path, "max_examples: -2\n")
+		if _, err := LoadConfig(path); err == nil {
+			t.Fatal("expected error for max_examples below -1")
+		}
+	})
+
+	t.Run("validates prompt template", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "config.yaml")
+		writeFile(t, path, "prompt_template: |\n  {{.Persona}}\n  <task>{{.Task}}</task>\n")
+
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			t.Fatalf("LoadConfig error: %v", err)
+		}
+		if cfg.PromptTemplate != "{{.Persona}}\n<task>{{.Task}}</task>" {
+			t.Fatalf("unexpected prompt template %q", cfg.PromptTemplate)
+		}
+
+		writeFile(t, path, "prompt_template: \"{{.Persona}}\"\n")
+		if _, err := LoadConfig(path); err == nil {
+			t.Fatal("expected error for template without task")
+		}
+	})
+
 	t.Run("errors on missing name", func(t *testing.T) {
 		dir := t.TempDir()
 		path := filepath.Join(dir, "config.yaml")
@@ -0,0 +1,131 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+
+	"subagents-mcp/internal/agents"
+	"subagents-mcp/internal/validate"
+)
+
+// Limits applied to agent context files so standing context cannot crowd out
+// the task itself.
+const (
+	maxContextFileBytes  = 64 << 10
+	maxContextTotalBytes = 256 << 10
+)
+
+// workdirContextPrefix marks context_files globs that resolve against the
+// delegation working directory instead of the agent's source directory.
+const workdirContextPrefix = "workdir:"
+
+type contextFile struct {
+	Path    string
+	Content string
+}
+
+// loadContextFiles expands the agent's context_files globs. Matches must stay
+// inside their base directory; binary files, oversized files and anything past
+// the total budget are skipped.
+func loadContextFiles(logger *zap.Logger, agent agents.Agent, workdir string) ([]contextFile, error) {
+	var files []contextFile
+	seen := make(map[string]struct{})
+	total := 0
+	for _, pattern := range agent.ContextFiles {
+		base, label := agent.Dir, ""
+		if base == "" {
+			base = agent.Source
+		}
+		if strings.HasPrefix(pattern, workdirContextPrefix) {
+			pattern = strings.TrimPrefix(pattern, workdirContextPrefix)
+			base, label = workdir, workdirContextPrefix
+		}
+		if base == "" {
+			return nil, fmt.Errorf("context_files %q: agent %q has no source directory", pattern, agent.Name)
+		}
+		if filepath.IsAbs(pattern) {
+			return nil, fmt.Errorf("context_files %q must be relative", pattern)
+		}
+		root, err := filepath.EvalSymlinks(base)
+		if err != nil {
+			return nil, fmt.Errorf("context_files %q: %w", pattern, err)
+		}
+
+		matches, err := filepath.Glob(filepath.Join(root, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("context_files %q: %w", pattern, err)
+		}
+		for _, match := range matches {
+			resolved, err := validate.Within(root, match)
+			if err != nil {
+				return nil, fmt.Errorf("context_files %q: %w", pattern, err)
+			}
+			if _, ok := seen[resolved]; ok {
+				continue
+			}
+			seen[resolved] = struct{}{}
+
+			rel, _ := filepath.Rel(root, resolved)
+			display := label + filepath.ToSlash(rel)
+			info, err := os.Stat(resolved)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			if info.Size() > maxContextFileBytes || total+int(info.Size()) > maxContextTotalBytes {
+				logger.Info("skipping context file over size limit", zap.String("agent", agent.Name), zap.String("file", display), zap.Int64("bytes", info.Size()))
+				continue
+			}
+			content, err := os.ReadFile(resolved)
+			if err != nil {
+				return nil, fmt.Errorf("read context file %s: %w", display, err)
+			}
+			if isBinary(content) {
+				logger.Info("skipping binary context file", zap.String("agent", agent.Name), zap.String("file", display))
+				continue
+			}
+			total += len(content)
+			files = append(files, contextFile{Path: display, Content: string(content)})
+		}
+	}
+	return files, nil
+}
+
+func isBinary(content []byte) bool {
+	sample := content
+	if len(sample) > 8000 {
+		sample = sample[:8000]
+	}
+	return bytes.IndexByte(sample, 0) >= 0 || !utf8.Valid(content)
+}
+
+// renderContextFiles formats files as delimited sections for the prompt.
+func renderContextFiles(files []contextFile) string {
+	if len(files) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Context files:")
+	for _, f := range files {
+		fmt.Fprintf(&b, "\n<context_file path=%q>\n%s\n</context_file>", f.Path, strings.TrimRight(f.Content, "\n"))
+	}
+	return b.String()
+}
+
+// recordContextFiles notes which context files were included for the task.
+func recordContextFiles(ctx context.Context, files []contextFile) {
+	report := reportFrom(ctx)
+	if report == nil {
+		return
+	}
+	report.ContextFiles = report.ContextFiles[:0]
+	for _, f := range files {
+		report.ContextFiles = append(report.ContextFiles, f.Path)
+	}
+}
@@ -0,0 +1,69 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"subagents-mcp/internal/agents"
+)
+
+func writeContextFile(t *testing.T, path string, content []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestBuildAgentPromptIncludesContextFiles(t *testing.T) {
+	agentsDir := t.TempDir()
+	workdir := t.TempDir()
+	writeContextFile(t, filepath.Join(agentsDir, "guides", "style.md"), []byte("Use short sentences.\n"))
+	writeContextFile(t, filepath.Join(agentsDir, "guides", "logo.png"), []byte{0x89, 'P', 'N', 'G', 0, 0})
+	writeContextFile(t, filepath.Join(agentsDir, "guides", "huge.md"), []byte(strings.Repeat("x", maxContextFileBytes+1)))
+	writeContextFile(t, filepath.Join(workdir, "docs", "adr", "001.md"), []byte("Use Go."))
+
+	agent := agents.Agent{
+		Name:         "writer",
+		Persona:      "p",
+		Source:       agentsDir,
+		ContextFiles: []string{"guides/*", "workdir:docs/adr/*.md"},
+	}
+	report := &Report{}
+	prompt, err := buildAgentPrompt(WithReport(context.Background(), report), zap.NewNop(), agent, "write docs", workdir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "p\n\nContext files:\n<context_file path=\"guides/style.md\">\nUse short sentences.\n</context_file>\n<context_file path=\"workdir:docs/adr/001.md\">\nUse Go.\n</context_file>\n\nTask: write docs"
+	if prompt != expected {
+		t.Fatalf("unexpected prompt:\n%s", prompt)
+	}
+	if strings.Join(report.ContextFiles, ",") != "guides/style.md,workdir:docs/adr/001.md" {
+		t.Fatalf("unexpected reported files: %v", report.ContextFiles)
+	}
+}
+
+func TestLoadContextFilesRejectsEscapes(t *testing.T) {
+	parent := t.TempDir()
+	agentsDir := filepath.Join(parent, "agents")
+	writeContextFile(t, filepath.Join(parent, "secret.txt"), []byte("secret"))
+	writeContextFile(t, filepath.Join(agentsDir, "ok.md"), []byte("ok"))
+
+	agent := agents.Agent{Name: "a", Source: agentsDir, ContextFiles: []string{"../secret.txt"}}
+	if _, err := loadContextFiles(zap.NewNop(), agent, t.TempDir()); err == nil {
+		t.Fatal("expected error for glob escaping the agents dir")
+	}
+
+	agent.ContextFiles = []string{"/etc/hostname"}
+	if _, err := loadContextFiles(zap.NewNop(), agent, t.TempDir()); err == nil {
+		t.Fatal("expected error for absolute glob")
+	}
+}
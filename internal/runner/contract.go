@@ -0,0 +1,100 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"subagents-mcp/internal/agents"
+	"subagents-mcp/internal/schema"
+)
+
+// ErrOutputContract indicates the agent never produced output matching its
+// output schema within the allowed attempts.
+type ErrOutputContract struct {
+	Agent    string
+	Attempts int
+	Err      error
+}
+
+func (e *ErrOutputContract) Error() string {
+	return fmt.Sprintf("agent %q output did not match output_schema after %d attempt(s): %v", e.Agent, e.Attempts, e.Err)
+}
+
+func (e *ErrOutputContract) Unwrap() error {
+	return e.Err
+}
+
+var fencedBlockPattern = regexp.MustCompile("(?s)```(?:json|JSON)?[ \\t]*\\n(.*?)\\n?```")
+
+// ExtractJSON decodes the JSON value in output. The whole output is tried
+// first, then every fenced code block from last to first.
+func ExtractJSON(output string) (any, error) {
+	trimmed := strings.TrimSpace(output)
+	var value any
+	if err := json.Unmarshal([]byte(trimmed), &value); err == nil {
+		return value, nil
+	}
+
+	blocks := fencedBlockPattern.FindAllStringSubmatch(trimmed, -1)
+	for i := len(blocks) - 1; i >= 0; i-- {
+		if err := json.Unmarshal([]byte(strings.TrimSpace(blocks[i][1])), &value); err == nil {
+			return value, nil
+		}
+	}
+	return nil, errors.New("no JSON value found in output")
+}
+
+// RunWithOutputContract runs task through r. When the agent declares an
+// output schema, the output is parsed and validated; on a mismatch the task is
+// re-run with the validation errors until the agent's attempt limit is
+// reached. The parsed value is nil for agents without an output schema.
+func RunWithOutputContract(ctx context.Context, logger *zap.Logger, r AgentRunner, agent agents.Agent, task string, workdir string) (string, any, error) {
+	if agent.OutputSchema == nil {
+		output, err := r.Run(ctx, agent, task, workdir, agent.Model)
+		return output, nil, err
+	}
+
+	attempts := agent.OutputAttempts
+	if attempts <= 0 {
+		attempts = agents.DefaultOutputAttempts
+	}
+
+	currentTask := task
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		output, err := r.Run(ctx, agent, currentTask, workdir, agent.Model)
+		if err != nil {
+			return "", nil, err
+		}
+
+		value, err := ExtractJSON(output)
+		if err == nil {
+			err = schema.Validate(agent.OutputSchema, value)
+		}
+		if err == nil {
+			return output, value, nil
+		}
+
+		logger.Warn("agent output did not match output_schema",
+			zap.String("agent", agent.Name),
+			zap.Int("attempt", attempt),
+			zap.Error(err))
+		lastErr = err
+		currentTask = repairTask(task, output, err)
+	}
+
+	return "", nil, &ErrOutputContract{Agent: agent.Name, Attempts: attempts, Err: lastErr}
+}
+
+// repairTask asks the runner to correct a previous answer that failed
+// validation.
+func repairTask(task, previous string, validationErr error) string {
+	return fmt.Sprintf("%s\n\nYour previous answer did not satisfy the required output schema.\nPrevious answer:\n%s\n\nValidation errors: %v\n\nReply again with corrected JSON only.",
+		strings.TrimSpace(task), strings.TrimSpace(previous), validationErr)
+}
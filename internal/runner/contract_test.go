@@ -0,0 +1,107 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"subagents-mcp/internal/agents"
+)
+
+type scriptedRunner struct {
+	outputs []string
+	tasks   []string
+}
+
+func (s *scriptedRunner) Run(ctx context.Context, agent agents.Agent, task string, workdir string, model string) (string, error) {
+	s.tasks = append(s.tasks, task)
+	out := s.outputs[0]
+	if len(s.outputs) > 1 {
+		s.outputs = s.outputs[1:]
+	}
+	return out, nil
+}
+
+func TestExtractJSON(t *testing.T) {
+	cases := map[string]string{
+		"bare":   `{"ok": true}`,
+		"fenced": "Here you go:\n```json\n{\"ok\": true}\n```\nDone.",
+		"last":   "```json\n{\"ok\": false\n```\n```\n{\"ok\": true}\n```",
+	}
+	for name, output := range cases {
+		t.Run(name, func(t *testing.T) {
+			value, err := ExtractJSON(output)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			obj, ok := value.(map[string]any)
+			if !ok || obj["ok"] != true {
+				t.Fatalf("unexpected value: %#v", value)
+			}
+		})
+	}
+
+	if _, err := ExtractJSON("no json here"); err == nil {
+		t.Fatal("expected error without JSON")
+	}
+}
+
+func TestRunWithOutputContract(t *testing.T) {
+	outputSchema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"summary": map[string]any{"type": "string"}},
+		"required":   []any{"summary"},
+	}
+	agent := agents.Agent{Name: "a", Persona: "p", Description: "d", OutputSchema: outputSchema, OutputAttempts: 2}
+
+	t.Run("repairs invalid output", func(t *testing.T) {
+		r := &scriptedRunner{outputs: []string{`{"title": "x"}`, "```json\n{\"summary\": \"fixed\"}\n```"}}
+		_, value, err := RunWithOutputContract(context.Background(), zap.NewNop(), r, agent, "task", "/tmp")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if value.(map[string]any)["summary"] != "fixed" {
+			t.Fatalf("unexpected value: %#v", value)
+		}
+		if len(r.tasks) != 2 || !strings.Contains(r.tasks[1], "summary: is required") {
+			t.Fatalf("expected repair task with validation errors, got %q", r.tasks)
+		}
+	})
+
+	t.Run("gives up after attempts", func(t *testing.T) {
+		r := &scriptedRunner{outputs: []string{"not json"}}
+		_, _, err := RunWithOutputContract(context.Background(), zap.NewNop(), r, agent, "task", "/tmp")
+		var contractErr *ErrOutputContract
+		if !errors.As(err, &contractErr) {
+			t.Fatalf("expected ErrOutputContract, got %v", err)
+		}
+		if len(r.tasks) != 2 {
+			t.Fatalf("expected 2 attempts, got %d", len(r.tasks))
+		}
+	})
+
+	t.Run("passes through without schema", func(t *testing.T) {
+		r := &scriptedRunner{outputs: []string{"plain"}}
+		out, value, err := RunWithOutputContract(context.Background(), zap.NewNop(), r, agents.Agent{Name: "a"}, "task", "/tmp")
+		if err != nil || out != "plain" || value != nil {
+			t.Fatalf("unexpected result: %q %#v %v", out, value, err)
+		}
+	})
+}
+
+func TestBuildAgentPromptIncludesOutputInstructions(t *testing.T) {
+	agent := agents.Agent{Name: "a", Persona: "p", OutputSchema: map[string]any{"type": "object"}}
+	prompt, err := buildAgentPrompt(context.Background(), zap.NewNop(), agent, "task", "/tmp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(prompt, "p\n\nTask: task\n\nOutput format:") {
+		t.Fatalf("unexpected prompt: %q", prompt)
+	}
+	if !strings.Contains(prompt, `"type": "object"`) {
+		t.Fatalf("expected schema in prompt: %q", prompt)
+	}
+}
@@ -30,6 +30,17 @@ func NewCopilotRunner(logger *zap.Logger, supportedModels []string) *CopilotRunn
 	}
 }
 
+// supports reports whether copilot can honor the agent's permissions and
+// tool lists.
+func (c *CopilotRunner) supports(agent agents.Agent) error {
+	permissionArgs, err := copilotPermissionArgs(agent.Permissions)
+	if err != nil {
+		return err
+	}
+	_, err = copilotToolArgs(permissionArgs, agent.AllowedTools, agent.DeniedTools)
+	return err
+}
+
 func (c *CopilotRunner) Run(ctx context.Context, agent agents.Agent, task string, workdir string, model string) (string, error) {
 	if task == "" {
 		return "", errors.New("task is required")
@@ -43,19 +54,34 @@ func (c *CopilotRunner) Run(ctx context.Context, agent agents.Agent, task string
 		return "", fmt.Errorf("model %q not supported by copilot runner", model)
 	}
 
-	prompt := buildAgentPrompt(agent, task)
+	permissionArgs, err := copilotPermissionArgs(agent.Permissions)
+	if err != nil {
+		return "", err
+	}
+	permissionArgs, err = copilotToolArgs(permissionArgs, agent.AllowedTools, agent.DeniedTools)
+	if err != nil {
+		return "", err
+	}
+
+	env, err := agentEnv(agent)
+	if err != nil {
+		return "", err
+	}
 
-	args := []string{
-		"-p", prompt,
-		"--allow-all-tools",
-		"--allow-all-paths",
-		"--stream", "off",
+	prompt, err := buildAgentPrompt(ctx, c.logger, agent, task, resolvedWorkdir)
+	if err != nil {
+		return "", err
 	}
+
+	args := append([]string{"-p", prompt}, permissionArgs...)
+	args = append(args, "--stream", "off")
 	if model != "" {
 		args = append([]string{"--model", model}, args...)
 	}
 
 	cmd := c.execCommand(ctx, "copilot", args...)
+	configureProcess(cmd)
+	cmd.Env = env
 	cmd.Dir = resolvedWorkdir
 
 	var stdout, stderr bytes.Buffer
@@ -72,12 +98,16 @@ func (c *CopilotRunner) Run(ctx context.Context, agent agents.Agent, task string
 		zap.String("workdir", resolvedWorkdir),
 		zap.String("task", truncate(task, 200)),
 		zap.String("model", model),
+		zap.Strings("env_keys", envKeys(agent)),
 		zap.Duration("duration", duration),
 		zap.ByteString("stderr", stderr.Bytes()),
 		zap.Error(err),
 	)
 
 	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", &ErrTimeout{RunnerName: "copilot", Partial: strings.TrimSpace(stdout.String())}
+		}
 		combined := stderr.String() + stdout.String()
 		if isCopilotUsageLimitMessage(combined) {
 			return "", &ErrUsageLimitExceeded{
@@ -90,3 +120,57 @@ func (c *CopilotRunner) Run(ctx context.Context, agent agents.Agent, task string
 
 	return strings.TrimSpace(stdout.String()), nil
 }
+
+// copilotPermissionArgs maps a permission profile onto Copilot tool flags.
+// Copilot cannot confine shell commands to the workspace, so
+// workspace-write is not supported.
+func copilotPermissionArgs(profile string) ([]string, error) {
+	switch profile {
+	case "", agents.PermissionsFull:
+		return []string{"--allow-all-tools", "--allow-all-paths"}, nil
+	case agents.PermissionsReadOnly:
+		return []string{"--allow-all-tools", "--deny-tool", "write", "--deny-tool", "shell"}, nil
+	default:
+		return nil, &ErrUnsupported{RunnerName: "copilot", Reason: fmt.Sprintf("permissions %q not supported", profile)}
+	}
+}
+
+// copilotToolArgs narrows the permission flags with the agent's tool lists:
+// an allow list replaces --allow-all-tools with one --allow-tool per entry,
+// and every denied tool adds a --deny-tool. Allowing a tool the profile
+// denies is unsupported, since Copilot's deny flags win.
+func copilotToolArgs(permissionArgs, allowed, denied []string) ([]string, error) {
+	for i := 0; i+1 < len(permissionArgs); i++ {
+		if permissionArgs[i] != "--deny-tool" {
+			continue
+		}
+		for _, tool := range allowed {
+			if toolKind(tool) == permissionArgs[i+1] {
+				return nil, &ErrUnsupported{RunnerName: "copilot", Reason: fmt.Sprintf("allowed tool %q is denied by the permission profile", tool)}
+			}
+		}
+	}
+
+	args := make([]string, 0, len(permissionArgs)+2*(len(allowed)+len(denied)))
+	for _, arg := range permissionArgs {
+		if arg == "--allow-all-tools" && len(allowed) > 0 {
+			for _, tool := range allowed {
+				args = append(args, "--allow-tool", tool)
+			}
+			continue
+		}
+		args = append(args, arg)
+	}
+	for _, tool := range denied {
+		args = append(args, "--deny-tool", tool)
+	}
+	return args, nil
+}
+
+// toolKind strips the argument from a tool name: "shell(git:*)" is "shell".
+func toolKind(tool string) string {
+	if i := strings.Index(tool, "("); i >= 0 {
+		return tool[:i]
+	}
+	return tool
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"go.uber.org/zap"
@@ -144,3 +145,70 @@ func TestCopilotRunner_OtherErrorNotUsageLimit(t *testing.T) {
 		t.Fatalf("expected generic error, not ErrUsageLimitExceeded: %v", err)
 	}
 }
+
+func TestCopilotRunnerPermissionProfiles(t *testing.T) {
+	r := NewCopilotRunner(zap.NewNop(), nil)
+
+	dir := t.TempDir()
+	var gotArgs []string
+	r.execCommand = func(ctx context.Context, name string, arg ...string) *exec.Cmd {
+		gotArgs = append([]string(nil), arg...)
+		return exec.CommandContext(ctx, "echo", "ok")
+	}
+
+	agent := agents.Agent{Name: "agent", Persona: "p", Description: "d", Permissions: agents.PermissionsReadOnly}
+	if _, err := r.Run(context.Background(), agent, "task", dir, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"-p", "p\n\nTask: task", "--allow-all-tools", "--deny-tool", "write", "--deny-tool", "shell", "--stream", "off"}
+	if strings.Join(gotArgs, "|") != strings.Join(expected, "|") {
+		t.Fatalf("expected args %v got %v", expected, gotArgs)
+	}
+
+	gotArgs = nil
+	agent.Permissions = agents.PermissionsWorkspaceWrite
+	_, err := r.Run(context.Background(), agent, "task", dir, "")
+	if !IsUnsupportedError(err) {
+		t.Fatalf("expected ErrUnsupported for workspace-write, got %v", err)
+	}
+	if gotArgs != nil {
+		t.Fatal("expected copilot not to be executed for unsupported profile")
+	}
+}
+
+func TestCopilotToolArgs(t *testing.T) {
+	base, err := copilotPermissionArgs(agents.PermissionsFull)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := copilotToolArgs(base, []string{"shell(git:*)", "write"}, []string{"shell(rm)"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"--allow-tool", "shell(git:*)", "--allow-tool", "write", "--allow-all-paths", "--deny-tool", "shell(rm)"}
+	if strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+
+	got, err = copilotToolArgs(base, nil, []string{"write"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = []string{"--allow-all-tools", "--allow-all-paths", "--deny-tool", "write"}
+	if strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+
+	readOnly, err := copilotPermissionArgs(agents.PermissionsReadOnly)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, tool := range []string{"shell(git:*)", "shell", "write"} {
+		if _, err := copilotToolArgs(readOnly, []string{tool}, nil); !IsUnsupportedError(err) {
+			t.Fatalf("expected ErrUnsupported for %q under read-only, got %v", tool, err)
+		}
+	}
+	if _, err := copilotToolArgs(readOnly, []string{"read"}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
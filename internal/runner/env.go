@@ -0,0 +1,98 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"subagents-mcp/internal/agents"
+)
+
+// secretFilePrefix marks env values read from a file, e.g. "file:/run/secrets/key".
+const secretFilePrefix = "file:"
+
+// agentEnv returns the environment for an agent's runner process: the
+// server's environment overlaid with the agent's env entries. Values support
+// ${VAR} expansion and file: references; only a literal file: prefix reads a
+// file, so an expanded variable cannot turn into one. Other $ sequences, such
+// as $VAR, $$ or $1, are kept as written. It returns nil when the
+// agent sets no env so the command inherits the server environment
+// unchanged. Errors name the variable but never its value.
+func agentEnv(agent agents.Agent) ([]string, error) {
+	if len(agent.Env) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(agent.Env))
+	for key := range agent.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	env := os.Environ()
+	for _, key := range keys {
+		value := agent.Env[key]
+		if strings.HasPrefix(value, secretFilePrefix) {
+			path := expandBraced(strings.TrimPrefix(value, secretFilePrefix))
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("env %s for agent %q: read secret file: %w", key, agent.Name, err)
+			}
+			value = strings.TrimRight(string(content), "\r\n")
+		} else {
+			value = expandBraced(value)
+		}
+		env = append(env, key+"="+value)
+	}
+	return env, nil
+}
+
+// expandBraced replaces ${VAR} references with the variable's value and
+// leaves every other $ untouched, so literal values like passwords survive.
+func expandBraced(value string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(value, "${")
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(value[start:], '}')
+		if end < 0 {
+			break
+		}
+		name := value[start+2 : start+end]
+		b.WriteString(value[:start])
+		if isEnvName(name) {
+			b.WriteString(os.Getenv(name))
+		} else {
+			b.WriteString(value[start : start+end+1])
+		}
+		value = value[start+end+1:]
+	}
+	b.WriteString(value)
+	return b.String()
+}
+
+func isEnvName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, c := range name {
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// envKeys lists the agent's env variable names for logging; values are
+// deliberately left out.
+func envKeys(agent agents.Agent) []string {
+	keys := make([]string, 0, len(agent.Env))
+	for key := range agent.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
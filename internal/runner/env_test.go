@@ -0,0 +1,82 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"subagents-mcp/internal/agents"
+)
+
+func TestAgentEnv(t *testing.T) {
+	t.Setenv("SUBAGENTS_TEST_HOME", "/home/tester")
+	secret := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(secret, []byte("s3cr3t\n"), 0o600); err != nil {
+		t.Fatalf("write secret: %v", err)
+	}
+
+	env, err := agentEnv(agents.Agent{Name: "a", Env: map[string]string{
+		"CODEX_HOME": "${SUBAGENTS_TEST_HOME}/.codex",
+		"API_KEY":    "file:" + secret,
+		"GOFLAGS":    "-mod=mod",
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tail := strings.Join(env[len(env)-3:], " ")
+	if tail != "API_KEY=s3cr3t CODEX_HOME=/home/tester/.codex GOFLAGS=-mod=mod" {
+		t.Fatalf("unexpected env overlay: %s", tail)
+	}
+
+	if env, err := agentEnv(agents.Agent{Name: "a"}); err != nil || env != nil {
+		t.Fatalf("expected nil env without overrides, got %v (%v)", env, err)
+	}
+
+	t.Setenv("SUBAGENTS_TEST_REF", "file:"+secret)
+	env, err = agentEnv(agents.Agent{Name: "a", Env: map[string]string{"REF": "${SUBAGENTS_TEST_REF}"}})
+	if err != nil || env[len(env)-1] != "REF=file:"+secret {
+		t.Fatalf("expanded values must not be read as files, got %v (%v)", env[len(env)-1], err)
+	}
+
+	env, err = agentEnv(agents.Agent{Name: "a", Env: map[string]string{"PASSWORD": "pa$$w0rd$1x$SUBAGENTS_TEST_HOME${}${1x}${SUBAGENTS_TEST_HOME"}})
+	if err != nil || env[len(env)-1] != "PASSWORD=pa$$w0rd$1x$SUBAGENTS_TEST_HOME${}${1x}${SUBAGENTS_TEST_HOME" {
+		t.Fatalf("expected only ${VAR} to expand, got %v (%v)", env[len(env)-1], err)
+	}
+
+	_, err = agentEnv(agents.Agent{Name: "a", Env: map[string]string{"API_KEY": "file:/definitely/missing"}})
+	if err == nil || !strings.Contains(err.Error(), "API_KEY") {
+		t.Fatalf("expected error naming the variable, got %v", err)
+	}
+}
+
+func TestRunnerAppliesEnvWithoutLoggingValues(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	r := NewCodexRunner(zap.New(core), nil)
+	r.execCommand = func(ctx context.Context, name string, arg ...string) *exec.Cmd {
+		return exec.CommandContext(ctx, "sh", "-c", `printf %s "$API_KEY"`)
+	}
+
+	agent := agents.Agent{Name: "agent", Persona: "p", Description: "d", Env: map[string]string{"API_KEY": "top-secret"}}
+	out, err := r.Run(context.Background(), agent, "task", t.TempDir(), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "top-secret" {
+		t.Fatalf("expected env to reach the command, got %q", out)
+	}
+	if logs.Len() == 0 {
+		t.Fatal("expected the run to be logged")
+	}
+	for _, entry := range logs.All() {
+		if logged := fmt.Sprint(entry.ContextMap()); strings.Contains(logged, "top-secret") {
+			t.Fatalf("secret leaked into logs: %s", logged)
+		}
+	}
+}
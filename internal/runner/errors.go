@@ -23,6 +23,44 @@ func IsUsageLimitError(err error) bool {
 	return errors.As(err, &usageErr)
 }
 
+// ErrTimeout indicates the runner did not finish before its deadline. Partial
+// holds whatever output the runner produced before it was stopped. The
+// selector treats timeouts like usage limits and tries the next runner.
+type ErrTimeout struct {
+	RunnerName string
+	Partial    string
+}
+
+func (e *ErrTimeout) Error() string {
+	if e.Partial == "" {
+		return fmt.Sprintf("%s: timed out", e.RunnerName)
+	}
+	return fmt.Sprintf("%s: timed out; partial output: %s", e.RunnerName, e.Partial)
+}
+
+// IsTimeoutError checks if an error indicates a runner deadline was hit.
+func IsTimeoutError(err error) bool {
+	var timeoutErr *ErrTimeout
+	return errors.As(err, &timeoutErr)
+}
+
+// ErrUnsupported indicates a runner cannot honor an agent's requirements,
+// such as its permission profile. The selector skips such runners.
+type ErrUnsupported struct {
+	RunnerName string
+	Reason     string
+}
+
+func (e *ErrUnsupported) Error() string {
+	return fmt.Sprintf("%s: %s", e.RunnerName, e.Reason)
+}
+
+// IsUnsupportedError checks if an error indicates the runner cannot serve the agent.
+func IsUnsupportedError(err error) bool {
+	var unsupportedErr *ErrUnsupported
+	return errors.As(err, &unsupportedErr)
+}
+
 // Usage limit detection patterns per runner.
 var codexUsageLimitPatterns = []string{
 	"you've hit your usage limit",
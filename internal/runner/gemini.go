@@ -32,6 +32,16 @@ func NewGeminiRunner(logger *zap.Logger, supportedModels []string) *GeminiRunner
 	}
 }
 
+// supports reports whether gemini can honor the agent's permissions and tool
+// lists.
+func (g *GeminiRunner) supports(agent agents.Agent) error {
+	if _, err := geminiPermissionArgs(agent.Permissions); err != nil {
+		return err
+	}
+	_, err := geminiToolArgs(agent.Permissions, agent.AllowedTools, agent.DeniedTools)
+	return err
+}
+
 // Run executes the Gemini CLI with the supplied prompt and model.
 func (g *GeminiRunner) Run(ctx context.Context, agent agents.Agent, task string, workdir string, model string) (string, error) {
 	if task == "" {
@@ -46,17 +56,38 @@ func (g *GeminiRunner) Run(ctx context.Context, agent agents.Agent, task string,
 		return "", fmt.Errorf("model %q not supported by gemini runner", model)
 	}
 
-	prompt := buildAgentPrompt(agent, task)
+	permissionArgs, err := geminiPermissionArgs(agent.Permissions)
+	if err != nil {
+		return "", err
+	}
+	toolArgs, err := geminiToolArgs(agent.Permissions, agent.AllowedTools, agent.DeniedTools)
+	if err != nil {
+		return "", err
+	}
+	permissionArgs = append(permissionArgs, toolArgs...)
+
+	env, err := agentEnv(agent)
+	if err != nil {
+		return "", err
+	}
+
+	prompt, err := buildAgentPrompt(ctx, g.logger, agent, task, resolvedWorkdir)
+	if err != nil {
+		return "", err
+	}
 
 	args := []string{
 		"-p", prompt,
 		"--output-format", "json",
 	}
+	args = append(args, permissionArgs...)
 	if model != "" {
 		args = append(args, "-m", model)
 	}
 
 	cmd := g.execCommand(ctx, "gemini", args...)
+	configureProcess(cmd)
+	cmd.Env = env
 	cmd.Dir = resolvedWorkdir
 
 	var stdout, stderr bytes.Buffer
@@ -73,12 +104,16 @@ func (g *GeminiRunner) Run(ctx context.Context, agent agents.Agent, task string,
 		zap.String("workdir", resolvedWorkdir),
 		zap.String("task", truncate(task, 200)),
 		zap.String("model", model),
+		zap.Strings("env_keys", envKeys(agent)),
 		zap.Duration("duration", duration),
 		zap.ByteString("stderr", stderr.Bytes()),
 		zap.Error(err),
 	)
 
 	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", &ErrTimeout{RunnerName: "gemini", Partial: strings.TrimSpace(stdout.String())}
+		}
 		combined := stderr.String() + stdout.String()
 		if isGeminiUsageLimitMessage(combined) {
 			return "", &ErrUsageLimitExceeded{
@@ -98,3 +133,77 @@ func (g *GeminiRunner) Run(ctx context.Context, agent agents.Agent, task string,
 	}
 	return output, nil
 }
+
+// geminiPermissionArgs maps a permission profile onto Gemini sandbox and
+// approval flags. In non-interactive mode tools that would need approval are
+// unavailable, so the approval mode decides what the agent may do.
+func geminiPermissionArgs(profile string) ([]string, error) {
+	switch profile {
+	case "":
+		return nil, nil
+	case agents.PermissionsReadOnly:
+		return []string{"--approval-mode", "default"}, nil
+	case agents.PermissionsWorkspaceWrite:
+		return []string{"--sandbox", "--approval-mode", "auto_edit"}, nil
+	case agents.PermissionsFull:
+		return []string{"--approval-mode", "yolo"}, nil
+	default:
+		return nil, &ErrUnsupported{RunnerName: "gemini", Reason: fmt.Sprintf("permissions %q not supported", profile)}
+	}
+}
+
+// geminiToolArgs maps allowed tools onto --allowed-tools. Gemini only uses
+// that list to skip confirmation, so it restricts anything only under
+// read-only and workspace-write, where other tools still need approval and
+// are unavailable non-interactively. Since an allowed tool is auto-approved,
+// tools the profile does not grant (writes or shell under read-only, shell
+// under workspace-write) are unsupported. Deny lists are not supported.
+func geminiToolArgs(profile string, allowed, denied []string) ([]string, error) {
+	if len(denied) > 0 {
+		return nil, &ErrUnsupported{RunnerName: "gemini", Reason: "denied_tools not supported"}
+	}
+	if len(allowed) == 0 {
+		return nil, nil
+	}
+	if profile != agents.PermissionsReadOnly && profile != agents.PermissionsWorkspaceWrite {
+		return nil, &ErrUnsupported{RunnerName: "gemini", Reason: "allowed_tools need permissions read-only or workspace-write"}
+	}
+	args := make([]string, 0, 2*len(allowed))
+	for _, tool := range allowed {
+		name := geminiToolName(tool)
+		if geminiToolExceeds(profile, toolKind(name)) {
+			return nil, &ErrUnsupported{RunnerName: "gemini", Reason: fmt.Sprintf("allowed tool %q exceeds permissions %q", tool, profile)}
+		}
+		args = append(args, "--allowed-tools", name)
+	}
+	return args, nil
+}
+
+// geminiToolExceeds reports whether auto-approving the Gemini tool kind would
+// grant more than profile allows.
+func geminiToolExceeds(profile, kind string) bool {
+	switch kind {
+	case "run_shell_command":
+		return true
+	case "write_file", "replace":
+		return profile == agents.PermissionsReadOnly
+	default:
+		return false
+	}
+}
+
+// geminiToolName translates Copilot-style tool names into Gemini's built-in
+// tool names; unknown names pass through unchanged.
+func geminiToolName(tool string) string {
+	switch {
+	case tool == "shell":
+		return "run_shell_command"
+	case strings.HasPrefix(tool, "shell(") && strings.HasSuffix(tool, ")"):
+		command := strings.TrimSuffix(strings.TrimPrefix(tool, "shell("), ")")
+		return "run_shell_command(" + strings.TrimSuffix(command, ":*") + ")"
+	case tool == "write":
+		return "write_file"
+	default:
+		return tool
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"go.uber.org/zap"
@@ -144,3 +145,75 @@ func TestGeminiRunnerOtherErrorNotUsageLimit(t *testing.T) {
 		t.Fatalf("expected generic error, not ErrUsageLimitExceeded: %v", err)
 	}
 }
+
+func TestGeminiPermissionArgs(t *testing.T) {
+	cases := map[string][]string{
+		"":                               nil,
+		agents.PermissionsReadOnly:       {"--approval-mode", "default"},
+		agents.PermissionsWorkspaceWrite: {"--sandbox", "--approval-mode", "auto_edit"},
+		agents.PermissionsFull:           {"--approval-mode", "yolo"},
+	}
+	for profile, want := range cases {
+		got, err := geminiPermissionArgs(profile)
+		if err != nil {
+			t.Fatalf("profile %q: unexpected error: %v", profile, err)
+		}
+		if strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Fatalf("profile %q: expected %v, got %v", profile, want, got)
+		}
+	}
+}
+
+func TestGeminiRunnerToolLists(t *testing.T) {
+	r := NewGeminiRunner(zap.NewNop(), nil)
+
+	dir := t.TempDir()
+	var gotArgs []string
+	r.execCommand = func(ctx context.Context, name string, arg ...string) *exec.Cmd {
+		gotArgs = append([]string(nil), arg...)
+		return exec.CommandContext(ctx, "echo", "ok")
+	}
+
+	agent := agents.Agent{Name: "agent", Persona: "p", Description: "d", Permissions: agents.PermissionsWorkspaceWrite, AllowedTools: []string{"write", "read_file"}}
+	if _, err := r.Run(context.Background(), agent, "task", dir, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "--allowed-tools write_file --allowed-tools read_file"
+	if !strings.Contains(strings.Join(gotArgs, " "), expected) {
+		t.Fatalf("expected %q in args %v", expected, gotArgs)
+	}
+
+	exceeding := map[string][]string{
+		agents.PermissionsReadOnly:       {"write", "shell(git:*)", "replace"},
+		agents.PermissionsWorkspaceWrite: {"shell(git:*)", "shell", "run_shell_command"},
+	}
+	for profile, tools := range exceeding {
+		for _, tool := range tools {
+			gotArgs = nil
+			denied := agent
+			denied.Permissions = profile
+			denied.AllowedTools = []string{"read_file", tool}
+			if _, err := r.Run(context.Background(), denied, "task", dir, ""); !IsUnsupportedError(err) || gotArgs != nil {
+				t.Fatalf("expected ErrUnsupported for %q under %q, got %v", tool, profile, err)
+			}
+		}
+	}
+
+	for _, profile := range []string{"", agents.PermissionsFull} {
+		gotArgs = nil
+		agent.Permissions = profile
+		if _, err := r.Run(context.Background(), agent, "task", dir, ""); !IsUnsupportedError(err) {
+			t.Fatalf("expected ErrUnsupported for allowed tools under %q, got %v", profile, err)
+		}
+		if gotArgs != nil {
+			t.Fatal("expected gemini not to be executed")
+		}
+	}
+
+	agent.Permissions = agents.PermissionsReadOnly
+	agent.AllowedTools = []string{"read_file"}
+	agent.DeniedTools = []string{"shell(rm)"}
+	if _, err := r.Run(context.Background(), agent, "task", dir, ""); !IsUnsupportedError(err) {
+		t.Fatalf("expected ErrUnsupported for denied tools, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package runner
+
+import "subagents-mcp/internal/agents"
+
+// Route is one (runner, model) pair the selector would try for an agent.
+// An empty Model means the runner's default model.
+type Route struct {
+	Runner string `json:"runner"`
+	Model  string `json:"model,omitempty"`
+}
+
+// Planner reports the routing a runner would use without executing anything.
+type Planner interface {
+	Plan(agent agents.Agent, model string) ([]Route, error)
+}
+
+// agentChecker is implemented by runners that can tell, without running,
+// that they cannot serve an agent (for example an unsupported permission
+// profile); supports then returns an ErrUnsupported.
+type agentChecker interface {
+	supports(agent agents.Agent) error
+}
+
+// attempt is a planned route together with the runner that serves it.
+type attempt struct {
+	Route
+	runner AgentRunner
+}
+
+// Plan lists, in order, the (runner, model) pairs Run would attempt for agent.
+func (s *Selector) Plan(agent agents.Agent, model string) ([]Route, error) {
+	attempts, _, err := s.plan(agent, model)
+	if err != nil {
+		return nil, err
+	}
+	routes := make([]Route, 0, len(attempts))
+	for _, a := range attempts {
+		routes = append(routes, a.Route)
+	}
+	return routes, nil
+}
+
+// plan returns the attempts Run makes for agent. Runners whose static checks
+// reject the agent are left out and their errors returned as skipped.
+func (s *Selector) plan(agent agents.Agent, model string) ([]attempt, []error, error) {
+	candidates, skipped, err := s.candidates(agent)
+	if err != nil {
+		return nil, nil, err
+	}
+	var attempts []attempt
+	usable := candidates[:0:0]
+	for _, candidate := range candidates {
+		if checker, ok := candidate.runner.(agentChecker); ok {
+			if err := checker.supports(agent); err != nil {
+				skipped = append(skipped, err)
+				continue
+			}
+		}
+		usable = append(usable, candidate)
+	}
+	for _, m := range modelChain(agent, model) {
+		for _, candidate := range usable {
+			if !supportsModel(candidate.models, m) {
+				continue
+			}
+			attempts = append(attempts, attempt{Route: Route{Runner: candidate.name, Model: m}, runner: candidate.runner})
+		}
+	}
+	return attempts, skipped, nil
+}
@@ -0,0 +1,83 @@
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"subagents-mcp/internal/agents"
+)
+
+func TestSelectorPlan(t *testing.T) {
+	origFactories := runnerFactories
+	defer func() { runnerFactories = origFactories }()
+
+	runnerFactories = map[string]func(*zap.Logger, []string) AgentRunner{
+		"codex":   func(_ *zap.Logger, _ []string) AgentRunner { return &fakeRunner{name: "codex"} },
+		"copilot": func(_ *zap.Logger, _ []string) AgentRunner { return &fakeRunner{name: "copilot"} },
+		"gemini":  func(_ *zap.Logger, _ []string) AgentRunner { return &fakeRunner{name: "gemini"} },
+	}
+
+	cfg := Config{Runners: []RunnerConfig{
+		{Name: "codex", Priority: 2, Models: []string{"gpt-5"}},
+		{Name: "copilot", Priority: 1, Models: []string{"gpt-5", "claude"}},
+	}}
+	selector, err := NewSelector(zap.NewNop(), cfg, "gemini")
+	if err != nil {
+		t.Fatalf("NewSelector error: %v", err)
+	}
+
+	agent := agents.Agent{Name: "a", Model: "gpt-5", Models: []string{"claude"}}
+	routes, err := selector.Plan(agent, agent.Model)
+	if err != nil {
+		t.Fatalf("Plan error: %v", err)
+	}
+	want := []Route{
+		{Runner: "gemini", Model: "gpt-5"},
+		{Runner: "copilot", Model: "gpt-5"},
+		{Runner: "codex", Model: "gpt-5"},
+		{Runner: "gemini", Model: "claude"},
+		{Runner: "copilot", Model: "claude"},
+	}
+	if len(routes) != len(want) {
+		t.Fatalf("expected %v, got %v", want, routes)
+	}
+	for i := range want {
+		if routes[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, routes)
+		}
+	}
+
+	if _, err := selector.Plan(agents.Agent{Name: "a", Runners: []string{"nope"}}, ""); err == nil {
+		t.Fatal("expected error for unknown runner")
+	}
+}
+
+func TestSelectorPlanSkipsUnsupportedRunners(t *testing.T) {
+	selector, err := NewSelector(zap.NewNop(), Config{}, "")
+	if err != nil {
+		t.Fatalf("NewSelector error: %v", err)
+	}
+
+	routes, err := selector.Plan(agents.Agent{Name: "a", Permissions: agents.PermissionsWorkspaceWrite}, "")
+	if err != nil {
+		t.Fatalf("Plan error: %v", err)
+	}
+	if len(routes) != 2 || routes[0].Runner != "codex" || routes[1].Runner != "gemini" {
+		t.Fatalf("expected copilot to be skipped for workspace-write, got %v", routes)
+	}
+
+	routes, err = selector.Plan(agents.Agent{Name: "a", Permissions: agents.PermissionsFull, AllowedTools: []string{"shell(git:*)"}}, "")
+	if err != nil {
+		t.Fatalf("Plan error: %v", err)
+	}
+	if len(routes) != 1 || routes[0].Runner != "copilot" {
+		t.Fatalf("expected only copilot to enforce the tool list, got %v", routes)
+	}
+
+	_, err = selector.Run(context.Background(), agents.Agent{Name: "a", Permissions: agents.PermissionsReadOnly, AllowedTools: []string{"write"}, DeniedTools: []string{"shell(rm)"}}, "t", t.TempDir(), "")
+	if !IsUnsupportedError(err) {
+		t.Fatalf("expected ErrUnsupported when every runner is skipped, got %v", err)
+	}
+}
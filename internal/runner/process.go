@@ -0,0 +1,18 @@
+package runner
+
+import (
+	"os/exec"
+	"time"
+)
+
+// processWaitDelay bounds how long Run waits for output pipes after the
+// runner process exits or is killed; grandchildren holding stdout or stderr
+// open would otherwise block it forever.
+const processWaitDelay = 2 * time.Second
+
+// configureProcess makes cancellation of cmd's context kill the runner CLI
+// together with every process it started.
+func configureProcess(cmd *exec.Cmd) {
+	cmd.WaitDelay = processWaitDelay
+	killProcessGroup(cmd)
+}
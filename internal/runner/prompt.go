@@ -1,24 +1,116 @@
 package runner
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 
+	"go.uber.org/zap"
+
 	"subagents-mcp/internal/agents"
 )
 
-// buildAgentPrompt injects the agent persona ahead of the task so the runner
-// has full context on the delegate's role.
-func buildAgentPrompt(agent agents.Agent, task string) string {
+// buildAgentPrompt injects the agent persona, any context files and worked
+// examples ahead of the task so the runner has full context on the delegate's
+// role.
+func buildAgentPrompt(ctx context.Context, logger *zap.Logger, agent agents.Agent, task string, workdir string) (string, error) {
+	files, err := loadContextFiles(logger, agent, workdir)
+	if err != nil {
+		return "", err
+	}
+	recordContextFiles(ctx, files)
+
 	persona := strings.TrimSpace(agent.Persona)
 	trimmedTask := strings.TrimSpace(task)
+	contextFiles := renderContextFiles(files)
+	examples := RenderExamples(agent)
+	instructions := outputInstructions(agent.OutputSchema)
 
+	if agent.PromptTemplate != "" {
+		vars := promptVariables(ctx, workdir, map[string]any{
+			"Persona":            persona,
+			"Task":               trimmedTask,
+			"ContextFiles":       contextFiles,
+			"Examples":           examples,
+			"OutputInstructions": instructions,
+		})
+		return renderPromptTemplate(agent, vars)
+	}
+
+	var sections []string
+	if persona != "" {
+		sections = append(sections, persona)
+	}
+	if contextFiles != "" {
+		sections = append(sections, contextFiles)
+	}
+	if examples != "" {
+		sections = append(sections, examples)
+	}
 	switch {
-	case persona == "":
-		return trimmedTask
 	case trimmedTask == "":
-		return persona
+	case len(sections) == 0:
+		sections = append(sections, trimmedTask)
 	default:
-		return fmt.Sprintf("%s\n\nTask: %s", persona, trimmedTask)
+		sections = append(sections, "Task: "+trimmedTask)
+	}
+	if instructions != "" {
+		sections = append(sections, instructions)
+	}
+	return strings.Join(sections, "\n\n"), nil
+}
+
+// RenderExamples formats the agent's examples as worked examples, keeping at
+// most the agent's MaxExamples (DefaultMaxExamples when unset, none for
+// agents.NoExamples).
+func RenderExamples(agent agents.Agent) string {
+	limit := agent.MaxExamples
+	switch {
+	case limit == 0:
+		limit = agents.DefaultMaxExamples
+	case limit < 0:
+		return ""
+	}
+	examples := agent.Examples
+	if len(examples) > limit {
+		examples = examples[:limit]
+	}
+	if len(examples) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("Worked examples:")
+	for _, example := range examples {
+		fmt.Fprintf(&b, "\n<example>\n<task>\n%s\n</task>\n<ideal_response>\n%s\n</ideal_response>\n</example>",
+			strings.TrimSpace(example.Input), strings.TrimSpace(example.Output))
+	}
+	return b.String()
+}
+
+// outputInstructions tells the runner how to shape its final answer when the
+// agent declares an output schema.
+func outputInstructions(outputSchema map[string]any) string {
+	if outputSchema == nil {
+		return ""
+	}
+	payload, err := json.MarshalIndent(outputSchema, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("Output format: respond with a single JSON value that conforms to this JSON Schema, inside a ```json fenced block and with no other JSON in the reply.\n```json\n%s\n```", payload)
+}
+
+// TaskWithInputs appends validated structured inputs to the task as a JSON
+// block so the runner sees them alongside the free-form instructions.
+func TaskWithInputs(task string, inputs map[string]any) (string, error) {
+	if len(inputs) == 0 {
+		return task, nil
+	}
+	payload, err := json.MarshalIndent(inputs, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshal inputs: %w", err)
 	}
+	return fmt.Sprintf("%s\n\nInputs:\n```json\n%s\n```", strings.TrimSpace(task), payload), nil
 }
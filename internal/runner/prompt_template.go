@@ -0,0 +1,34 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"subagents-mcp/internal/agents"
+)
+
+// renderPromptTemplate lays out the prompt with the agent's prompt_template.
+// When the template omits {{.OutputInstructions}} they are appended so output
+// contracts keep working.
+func renderPromptTemplate(agent agents.Agent, vars map[string]any) (string, error) {
+	if err := agents.CheckPromptTemplate(agent.PromptTemplate); err != nil {
+		return "", fmt.Errorf("prompt_template for agent %q: %w", agent.Name, err)
+	}
+	tmpl, err := template.New(agent.Name).Option("missingkey=error").Parse(agent.PromptTemplate)
+	if err != nil {
+		return "", fmt.Errorf("prompt_template for agent %q: %w", agent.Name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("render prompt_template for agent %q: %w", agent.Name, err)
+	}
+	prompt := strings.TrimSpace(buf.String())
+
+	instructions, _ := vars["OutputInstructions"].(string)
+	if instructions != "" && !strings.Contains(agent.PromptTemplate, ".OutputInstructions") {
+		prompt += "\n\n" + instructions
+	}
+	return prompt, nil
+}
@@ -0,0 +1,74 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"subagents-mcp/internal/agents"
+)
+
+func TestBuildAgentPromptUsesPromptTemplate(t *testing.T) {
+	origGit := gitOutput
+	defer func() { gitOutput = origGit }()
+	gitOutput = func(ctx context.Context, dir string, args ...string) (string, error) {
+		return "", context.Canceled
+	}
+
+	workdir := t.TempDir()
+	agent := agents.Agent{
+		Name:           "xml",
+		Persona:        "Be terse.",
+		Examples:       []agents.Example{{Input: "a", Output: "b"}},
+		OutputSchema:   map[string]any{"type": "object"},
+		PromptTemplate: "<task>{{.Task}}</task>\n<role>{{.Persona}}</role>\n{{.Examples}}\n<cwd>{{.WorkingDirectory}}</cwd>\nAnswer briefly.",
+	}
+	prompt, err := buildAgentPrompt(context.Background(), zap.NewNop(), agent, " review ", workdir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(prompt, "<task>review</task>\n<role>Be terse.</role>\nWorked examples:\n<example>") {
+		t.Fatalf("unexpected prompt start:\n%s", prompt)
+	}
+	if !strings.Contains(prompt, "<cwd>"+workdir+"</cwd>\nAnswer briefly.\n\nOutput format:") {
+		t.Fatalf("expected workdir and appended output instructions:\n%s", prompt)
+	}
+
+	agent.PromptTemplate = "{{.Persona}} {{.Nope}} {{.Task}}"
+	if _, err := buildAgentPrompt(context.Background(), zap.NewNop(), agent, "t", workdir); err == nil || !strings.Contains(err.Error(), "unknown variable {{.Nope}}") {
+		t.Fatalf("expected unknown variable error, got %v", err)
+	}
+	agent.PromptTemplate = "{{.Persona}}"
+	if _, err := buildAgentPrompt(context.Background(), zap.NewNop(), agent, "t", workdir); err == nil || !strings.Contains(err.Error(), "{{.Task}}") {
+		t.Fatalf("expected missing task error, got %v", err)
+	}
+}
+
+func TestBuildAgentPromptReusesPromptVariables(t *testing.T) {
+	origGit := gitOutput
+	defer func() { gitOutput = origGit }()
+	calls := 0
+	gitOutput = func(ctx context.Context, dir string, args ...string) (string, error) {
+		calls++
+		return "main", nil
+	}
+
+	workdir := t.TempDir()
+	ctx := WithPromptVariables(context.Background(), PromptVariables(context.Background(), workdir, nil))
+	calls = 0
+	agent := agents.Agent{Name: "a", Persona: "p", PromptTemplate: "{{.GitBranch}}: {{.Task}}"}
+	for i := 0; i < 2; i++ {
+		prompt, err := buildAgentPrompt(ctx, zap.NewNop(), agent, "t", workdir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if prompt != "main: t" {
+			t.Fatalf("unexpected prompt %q", prompt)
+		}
+	}
+	if calls != 0 {
+		t.Fatalf("expected carried variables to skip git, got %d calls", calls)
+	}
+}
@@ -0,0 +1,59 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"subagents-mcp/internal/agents"
+)
+
+func TestTaskWithInputs(t *testing.T) {
+	got, err := TaskWithInputs(" review ", map[string]any{"paths": []any{"a.go"}, "base_ref": "main"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "review\n\nInputs:\n```json\n{\n  \"base_ref\": \"main\",\n  \"paths\": [\n    \"a.go\"\n  ]\n}\n```"
+	if got != expected {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+
+	got, err = TaskWithInputs("review", nil)
+	if err != nil || got != "review" {
+		t.Fatalf("expected task unchanged without inputs, got %q (%v)", got, err)
+	}
+}
+
+func TestBuildAgentPromptIncludesExamples(t *testing.T) {
+	agent := agents.Agent{
+		Name:    "changelog",
+		Persona: "p",
+		Examples: []agents.Example{
+			{Input: "fix: typo", Output: "- Fixed a typo."},
+			{Input: "feat: export", Output: "- Added export."},
+			{Input: "chore: deps", Output: "- Updated dependencies."},
+		},
+		MaxExamples: 2,
+	}
+	prompt, err := buildAgentPrompt(context.Background(), zap.NewNop(), agent, "summarise", t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "p\n\nWorked examples:\n<example>\n<task>\nfix: typo\n</task>\n<ideal_response>\n- Fixed a typo.\n</ideal_response>\n</example>\n<example>\n<task>\nfeat: export\n</task>\n<ideal_response>\n- Added export.\n</ideal_response>\n</example>\n\nTask: summarise"
+	if prompt != expected {
+		t.Fatalf("unexpected prompt:\n%s", prompt)
+	}
+
+	agent.MaxExamples = 0
+	agent.Examples = append(agent.Examples, agents.Example{Input: "docs: readme", Output: "- Docs."})
+	if got := strings.Count(RenderExamples(agent), "<example>"); got != agents.DefaultMaxExamples {
+		t.Fatalf("expected default cap of %d examples, got %d", agents.DefaultMaxExamples, got)
+	}
+
+	agent.MaxExamples = agents.NoExamples
+	if got := RenderExamples(agent); got != "" {
+		t.Fatalf("expected no examples, got %q", got)
+	}
+}
@@ -0,0 +1,24 @@
+package runner
+
+import "context"
+
+// Report records how a delegated task was served. Attach one to the context
+// with WithReport and the selector fills it in once a runner succeeds.
+type Report struct {
+	Runner string
+	Model  string
+	// ContextFiles lists the context files included in the prompt.
+	ContextFiles []string
+}
+
+type reportKey struct{}
+
+// WithReport returns a context that carries report.
+func WithReport(ctx context.Context, report *Report) context.Context {
+	return context.WithValue(ctx, reportKey{}, report)
+}
+
+func reportFrom(ctx context.Context) *Report {
+	report, _ := ctx.Value(reportKey{}).(*Report)
+	return report
+}
@@ -0,0 +1,110 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"subagents-mcp/internal/agents"
+)
+
+// lookPath is overridable in tests.
+var lookPath = exec.LookPath
+
+// runnerBinaries maps runner names to the CLI each one executes.
+var runnerBinaries = map[string]string{
+	"codex":   "codex",
+	"copilot": "copilot",
+	"gemini":  "gemini",
+}
+
+// CheckRequirements reports why agent is unavailable, or nil when every
+// condition in agent.Requires holds. Runner and model conditions need the
+// runner's CLI on PATH and, when planner is set, a route through it; without
+// a planner model conditions are not checked. File conditions are only
+// evaluated when workdir is set.
+func CheckRequirements(agent agents.Agent, workdir string, planner Planner) error {
+	req := agent.Requires
+	if req == nil {
+		return nil
+	}
+
+	var unmet []string
+	if len(req.Runners) > 0 && !anyRunnerReachable(agent, req.Runners, planner) {
+		unmet = append(unmet, fmt.Sprintf("none of the runners %q is installed and configured", req.Runners))
+	}
+	if len(req.Models) > 0 && planner != nil && !anyModelReachable(agent, req.Models, planner) {
+		unmet = append(unmet, fmt.Sprintf("none of the models %q is served by an installed runner", req.Models))
+	}
+	for _, key := range req.Env {
+		if agent.Env[key] == "" && os.Getenv(key) == "" {
+			unmet = append(unmet, fmt.Sprintf("environment variable %s is not set", key))
+		}
+	}
+	if workdir != "" {
+		for _, pattern := range req.Files {
+			matches, err := filepath.Glob(filepath.Join(workdir, pattern))
+			if err != nil || len(matches) == 0 {
+				unmet = append(unmet, fmt.Sprintf("%s not found in the working directory", pattern))
+			}
+		}
+	}
+	if len(unmet) > 0 {
+		return errors.New(strings.Join(unmet, "; "))
+	}
+	return nil
+}
+
+func anyRunnerReachable(agent agents.Agent, names []string, planner Planner) bool {
+	var routed map[string]bool
+	if planner != nil {
+		routes, err := planner.Plan(agent, agent.Model)
+		if err != nil {
+			return false
+		}
+		routed = make(map[string]bool, len(routes))
+		for _, route := range routes {
+			routed[route.Runner] = true
+		}
+	}
+	for _, name := range names {
+		if routed != nil && !routed[name] {
+			continue
+		}
+		if installed(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// anyModelReachable reports whether some installed runner has a route for
+// one of models.
+func anyModelReachable(agent agents.Agent, models []string, planner Planner) bool {
+	for _, model := range models {
+		probe := agent
+		probe.Model, probe.Models = model, nil
+		routes, err := planner.Plan(probe, model)
+		if err != nil {
+			continue
+		}
+		for _, route := range routes {
+			if installed(route.Runner) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func installed(name string) bool {
+	binary, ok := runnerBinaries[name]
+	if !ok {
+		return false
+	}
+	_, err := lookPath(binary)
+	return err == nil
+}
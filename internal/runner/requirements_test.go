@@ -0,0 +1,92 @@
+package runner
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"subagents-mcp/internal/agents"
+)
+
+func TestCheckRequirements(t *testing.T) {
+	origLookPath := lookPath
+	defer func() { lookPath = origLookPath }()
+	lookPath = func(file string) (string, error) {
+		if file == "gemini" {
+			return "/usr/bin/gemini", nil
+		}
+		return "", errors.New("not found")
+	}
+	t.Setenv("SUBAGENTS_TEST_TOKEN", "x")
+
+	workdir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workdir, "go.mod"), []byte("module x\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	met := agents.Agent{Name: "a", Requires: &agents.Requirements{
+		Runners: []string{"codex", "gemini"},
+		Env:     []string{"SUBAGENTS_TEST_TOKEN"},
+		Files:   []string{"go.mod"},
+	}}
+	if err := CheckRequirements(met, workdir, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	unmet := agents.Agent{Name: "a", Requires: &agents.Requirements{
+		Runners: []string{"codex"},
+		Env:     []string{"SUBAGENTS_TEST_MISSING"},
+		Files:   []string{"package.json"},
+	}}
+	err := CheckRequirements(unmet, workdir, nil)
+	if err == nil {
+		t.Fatal("expected unmet requirements")
+	}
+	for _, want := range []string{`runners ["codex"] is installed and configured`, "SUBAGENTS_TEST_MISSING is not set", "package.json not found"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in %v", want, err)
+		}
+	}
+
+	if err := CheckRequirements(agents.Agent{Name: "a", Requires: &agents.Requirements{Files: []string{"package.json"}}}, "", nil); err != nil {
+		t.Fatalf("file conditions need a working directory: %v", err)
+	}
+
+	ownEnv := agents.Agent{Name: "a", Env: map[string]string{"SUBAGENTS_TEST_MISSING": "set"}, Requires: &agents.Requirements{Env: []string{"SUBAGENTS_TEST_MISSING"}}}
+	if err := CheckRequirements(ownEnv, "", nil); err != nil {
+		t.Fatalf("expected the agent's own env to satisfy requires.env: %v", err)
+	}
+}
+
+func TestCheckRequirementsUsesSelectorRoutes(t *testing.T) {
+	origLookPath := lookPath
+	defer func() { lookPath = origLookPath }()
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+
+	selector, err := NewSelector(zap.NewNop(), Config{Runners: []RunnerConfig{
+		{Name: "codex", Models: []string{"gpt-5"}},
+	}}, "")
+	if err != nil {
+		t.Fatalf("NewSelector error: %v", err)
+	}
+
+	configured := agents.Agent{Name: "a", Requires: &agents.Requirements{Runners: []string{"codex"}, Models: []string{"claude", "gpt-5"}}}
+	if err := CheckRequirements(configured, "", selector); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	unrouted := agents.Agent{Name: "a", Requires: &agents.Requirements{Runners: []string{"gemini"}, Models: []string{"claude"}}}
+	err = CheckRequirements(unrouted, "", selector)
+	if err == nil {
+		t.Fatal("expected runners outside the config and unserved models to be unmet")
+	}
+	for _, want := range []string{`runners ["gemini"]`, `models ["claude"]`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in %v", want, err)
+		}
+	}
+}
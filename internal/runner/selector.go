@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -37,6 +38,12 @@ type Selector struct {
 	logger    *zap.Logger
 	preferred *namedRunner
 	fallbacks []namedRunner
+	// restricted is set when the runner config lists runners; agents may
+	// then only use those (and the preferred runner).
+	restricted     bool
+	defaultTimeout time.Duration
+	maxExamples    int
+	promptTemplate string
 }
 
 // NewSelector builds a model-aware runner selector using a preferred runner name
@@ -66,14 +73,19 @@ func NewSelector(logger *zap.Logger, cfg Config, preferred string) (*Selector, e
 		return entries[i].priority < entries[j].priority
 	})
 
+	restricted := len(entries) > 0
 	if preferred == "" {
 		if len(entries) == 0 {
 			entries = defaultRunnerEntries(logger)
 		}
 		return &Selector{
-			logger:    logger,
-			preferred: nil,
-			fallbacks: entries,
+			logger:         logger,
+			preferred:      nil,
+			fallbacks:      entries,
+			restricted:     restricted,
+			defaultTimeout: cfg.DefaultTimeout,
+			maxExamples:    cfg.MaxExamples,
+			promptTemplate: cfg.PromptTemplate,
 		}, nil
 	}
 
@@ -107,9 +119,13 @@ func NewSelector(logger *zap.Logger, cfg Config, preferred string) (*Selector, e
 	}
 
 	return &Selector{
-		logger:    logger,
-		preferred: preferredRunner,
-		fallbacks: fallbacks,
+		logger:         logger,
+		preferred:      preferredRunner,
+		fallbacks:      fallbacks,
+		restricted:     restricted,
+		defaultTimeout: cfg.DefaultTimeout,
+		maxExamples:    cfg.MaxExamples,
+		promptTemplate: cfg.PromptTemplate,
 	}, nil
 }
 
@@ -130,38 +146,163 @@ func defaultRunnerEntries(logger *zap.Logger) []namedRunner {
 	return entries
 }
 
-func (s *Selector) Run(ctx context.Context, agent agents.Agent, task string, workdir string, model string) (string, error) {
-	candidates := make([]namedRunner, 0, 1+len(s.fallbacks))
+// candidates returns the runners to try for agent, in order. Agents that list
+// their own runners get exactly that chain; others use the preferred runner
+// followed by the configured fallbacks. When the runner config lists
+// runners, agent runners missing from it are skipped with an *ErrUnsupported
+// rather than run without its model restrictions.
+func (s *Selector) candidates(agent agents.Agent) ([]namedRunner, []error, error) {
+	configured := make([]namedRunner, 0, 1+len(s.fallbacks))
 	if s.preferred != nil {
-		candidates = append(candidates, *s.preferred)
+		configured = append(configured, *s.preferred)
+	}
+	configured = append(configured, s.fallbacks...)
+	if len(agent.Runners) == 0 {
+		return configured, nil, nil
 	}
-	candidates = append(candidates, s.fallbacks...)
 
-	var lastUsageLimitErr error
-	for _, candidate := range candidates {
-		if !supportsModel(candidate.models, model) {
+	chain := make([]namedRunner, 0, len(agent.Runners))
+	var skipped []error
+	for _, name := range agent.Runners {
+		found := false
+		for _, entry := range configured {
+			if entry.name == name {
+				chain = append(chain, entry)
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		build, ok := runnerFactories[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("agent %q lists unknown runner %q", agent.Name, name)
+		}
+		if s.restricted {
+			skipped = append(skipped, &ErrUnsupported{RunnerName: name, Reason: "not in the runner config"})
 			continue
 		}
-		output, err := candidate.runner.Run(ctx, agent, task, workdir, model)
+		chain = append(chain, namedRunner{name: name, runner: build(s.logger, nil)})
+	}
+	return chain, skipped, nil
+}
+
+func (s *Selector) Run(ctx context.Context, agent agents.Agent, task string, workdir string, model string) (string, error) {
+	attempts, skipped, err := s.plan(agent, model)
+	if err != nil {
+		return "", err
+	}
+
+	models := modelChain(agent, model)
+
+	timeout := agent.Timeout
+	if timeout == 0 {
+		timeout = s.defaultTimeout
+	}
+	if agent.MaxExamples == 0 {
+		agent.MaxExamples = s.maxExamples
+	}
+	if agent.PromptTemplate == "" {
+		agent.PromptTemplate = s.promptTemplate
+	}
+
+	var lastUsageLimitErr, lastTimeoutErr, lastUnsupportedErr error
+	for _, err := range skipped {
+		s.logger.Info("runner cannot serve agent, skipping",
+			zap.String("agent", agent.Name),
+			zap.Error(err))
+		lastUnsupportedErr = err
+	}
+	for _, a := range attempts {
+		output, err := runAttempt(ctx, a.runner, agent, task, workdir, a.Model, timeout)
 		if err == nil {
+			s.logger.Info("task served",
+				zap.String("agent", agent.Name),
+				zap.String("agent_version", agent.Version),
+				zap.String("definition_hash", agent.DefinitionHash),
+				zap.String("runner", a.Runner),
+				zap.String("model", a.Model))
+			if report := reportFrom(ctx); report != nil {
+				report.Runner = a.Runner
+				report.Model = a.Model
+			}
 			return output, nil
 		}
 		if IsUsageLimitError(err) {
 			s.logger.Warn("runner hit usage limit, trying next",
-				zap.String("runner", candidate.name),
+				zap.String("runner", a.Runner),
+				zap.String("model", a.Model),
 				zap.Error(err))
 			lastUsageLimitErr = err
 			continue
 		}
-		// Non-usage-limit error: fail immediately
+		if IsUnsupportedError(err) {
+			s.logger.Info("runner cannot serve agent, skipping",
+				zap.String("runner", a.Runner),
+				zap.String("agent", agent.Name),
+				zap.Error(err))
+			lastUnsupportedErr = err
+			continue
+		}
+		if IsTimeoutError(err) && ctx.Err() == nil {
+			s.logger.Warn("runner timed out, trying next",
+				zap.String("runner", a.Runner),
+				zap.String("model", a.Model),
+				zap.Duration("timeout", timeout))
+			lastTimeoutErr = err
+			continue
+		}
+		// Other errors: fail immediately
 		return "", err
 	}
 
 	if lastUsageLimitErr != nil {
 		return "", fmt.Errorf("all runners exhausted due to usage limits: %w", lastUsageLimitErr)
 	}
-	if model == "" {
+	if lastTimeoutErr != nil {
+		return "", fmt.Errorf("all runners timed out after %s: %w", timeout, lastTimeoutErr)
+	}
+	if lastUnsupportedErr != nil {
+		return "", fmt.Errorf("no runner can serve agent %q: %w", agent.Name, lastUnsupportedErr)
+	}
+	switch {
+	case len(models) > 1:
+		return "", fmt.Errorf("no runner supports any of models %q", models)
+	case models[0] == "":
 		return "", fmt.Errorf("no runner available")
+	default:
+		return "", fmt.Errorf("no runner supports model %q", models[0])
+	}
+}
+
+// runAttempt runs a single candidate, bounding it by timeout when set.
+func runAttempt(ctx context.Context, r AgentRunner, agent agents.Agent, task, workdir, model string, timeout time.Duration) (string, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return r.Run(ctx, agent, task, workdir, model)
+}
+
+// modelChain lists the models to try in order: the requested model followed
+// by the agent's fallback models. An empty entry means "runner default".
+func modelChain(agent agents.Agent, model string) []string {
+	var chain []string
+	seen := make(map[string]struct{})
+	for _, m := range append([]string{model}, agent.Models...) {
+		if m == "" {
+			continue
+		}
+		if _, ok := seen[m]; ok {
+			continue
+		}
+		seen[m] = struct{}{}
+		chain = append(chain, m)
+	}
+	if len(chain) == 0 {
+		return []string{""}
 	}
-	return "", fmt.Errorf("no runner supports model %q", model)
+	return chain
 }
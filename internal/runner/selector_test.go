@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -24,6 +25,20 @@ func (f *fakeRunner) Run(ctx context.Context, agent agents.Agent, task string, w
 	return f.output, f.runErr
 }
 
+func TestRunnerNamesMatchFactories(t *testing.T) {
+	if len(agents.RunnerNames) != len(runnerFactories) {
+		t.Fatalf("agents.RunnerNames %v does not match the runner factories", agents.RunnerNames)
+	}
+	for _, name := range agents.RunnerNames {
+		if _, ok := runnerFactories[name]; !ok {
+			t.Fatalf("no runner factory for %q", name)
+		}
+		if _, ok := runnerBinaries[name]; !ok {
+			t.Fatalf("no runner binary for %q", name)
+		}
+	}
+}
+
 func TestSelectorPrefersFlagAndFallsBack(t *testing.T) {
 	origFactories := runnerFactories
 	defer func() { runnerFactories = origFactories }()
@@ -310,3 +325,311 @@ func TestSelector_NonUsageLimitErrorNoFallback(t *testing.T) {
 		t.Fatal("expected copilot NOT to be called for non-usage-limit error")
 	}
 }
+
+func TestSelector_AgentRunnersOverrideOrder(t *testing.T) {
+	origFactories := runnerFactories
+	defer func() { runnerFactories = origFactories }()
+
+	codex := &fakeRunner{
+		name:   "codex",
+		runErr: &ErrUsageLimitExceeded{RunnerName: "codex", Message: "quota"},
+	}
+	copilot := &fakeRunner{name: "copilot", output: "copilot-out"}
+	gemini := &fakeRunner{
+		name:   "gemini",
+		runErr: &ErrUsageLimitExceeded{RunnerName: "gemini", Message: "quota"},
+	}
+
+	runnerFactories = map[string]func(*zap.Logger, []string) AgentRunner{
+		"codex":   func(_ *zap.Logger, _ []string) AgentRunner { return codex },
+		"copilot": func(_ *zap.Logger, _ []string) AgentRunner { return copilot },
+		"gemini":  func(_ *zap.Logger, _ []string) AgentRunner { return gemini },
+	}
+
+	cfg := Config{
+		Runners: []RunnerConfig{
+			{Name: "copilot", Priority: 1},
+			{Name: "codex", Priority: 2},
+		},
+	}
+
+	selector, err := NewSelector(zap.NewNop(), cfg, "copilot")
+	if err != nil {
+		t.Fatalf("NewSelector error: %v", err)
+	}
+
+	agent := agents.Agent{Name: "a", Persona: "p", Description: "d", Runners: []string{"gemini", "codex"}}
+	_, err = selector.Run(context.Background(), agent, "task", "/tmp", "")
+	if !IsUsageLimitError(err) {
+		t.Fatalf("expected usage limit error after agent chain exhausted, got %v", err)
+	}
+	if gemini.called || !codex.called {
+		t.Fatalf("expected gemini (not in the runner config) to be skipped and codex tried, got gemini=%v codex=%v", gemini.called, codex.called)
+	}
+	if copilot.called {
+		t.Fatal("expected copilot to be skipped when not in agent runners")
+	}
+
+	agent.Runners = []string{"codex", "copilot"}
+	out, err := selector.Run(context.Background(), agent, "task", "/tmp", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "copilot-out" {
+		t.Fatalf("expected copilot output after codex usage limit, got %q", out)
+	}
+
+	agent.Runners = []string{"gemini"}
+	if _, err := selector.Run(context.Background(), agent, "task", "/tmp", ""); !IsUnsupportedError(err) || gemini.called {
+		t.Fatalf("expected unconfigured runner to be unsupported, got %v", err)
+	}
+
+	agent.Runners = []string{"unknown"}
+	if _, err := selector.Run(context.Background(), agent, "task", "/tmp", ""); err == nil {
+		t.Fatal("expected error for unknown runner")
+	}
+}
+
+func TestSelector_AgentRunnersHonorModelSupport(t *testing.T) {
+	origFactories := runnerFactories
+	defer func() { runnerFactories = origFactories }()
+
+	codex := &fakeRunner{name: "codex", output: "codex-out"}
+	gemini := &fakeRunner{name: "gemini", output: "gemini-out"}
+
+	runnerFactories = map[string]func(*zap.Logger, []string) AgentRunner{
+		"codex":  func(_ *zap.Logger, _ []string) AgentRunner { return codex },
+		"gemini": func(_ *zap.Logger, _ []string) AgentRunner { return gemini },
+	}
+
+	cfg := Config{
+		Runners: []RunnerConfig{
+			{Name: "gemini", Priority: 1, Models: []string{"gemini-2.5-pro"}},
+			{Name: "codex", Priority: 2, Models: []string{"gpt-5"}},
+		},
+	}
+
+	selector, err := NewSelector(zap.NewNop(), cfg, "")
+	if err != nil {
+		t.Fatalf("NewSelector error: %v", err)
+	}
+
+	agent := agents.Agent{Name: "a", Persona: "p", Description: "d", Runners: []string{"gemini", "codex"}}
+	out, err := selector.Run(context.Background(), agent, "task", "/tmp", "gpt-5")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "codex-out" || gemini.called {
+		t.Fatalf("expected gemini skipped for unsupported model, got %q gemini=%v", out, gemini.called)
+	}
+}
+
+func TestSelector_WalksModelRunnerPairs(t *testing.T) {
+	origFactories := runnerFactories
+	defer func() { runnerFactories = origFactories }()
+
+	codex := &fakeRunner{
+		name:   "codex",
+		runErr: &ErrUsageLimitExceeded{RunnerName: "codex", Message: "quota"},
+	}
+	copilot := &fakeRunner{name: "copilot", output: "copilot-out"}
+	gemini := &fakeRunner{name: "gemini", output: "gemini-out"}
+
+	runnerFactories = map[string]func(*zap.Logger, []string) AgentRunner{
+		"codex":   func(_ *zap.Logger, _ []string) AgentRunner { return codex },
+		"copilot": func(_ *zap.Logger, _ []string) AgentRunner { return copilot },
+		"gemini":  func(_ *zap.Logger, _ []string) AgentRunner { return gemini },
+	}
+
+	cfg := Config{
+		Runners: []RunnerConfig{
+			{Name: "codex", Priority: 1, Models: []string{"gpt-5.1-codex"}},
+			{Name: "copilot", Priority: 2, Models: []string{"claude-sonnet-4.5"}},
+			{Name: "gemini", Priority: 3, Models: []string{"gemini-2.5-pro"}},
+		},
+	}
+
+	selector, err := NewSelector(zap.NewNop(), cfg, "")
+	if err != nil {
+		t.Fatalf("NewSelector error: %v", err)
+	}
+
+	agent := agents.Agent{
+		Name:        "a",
+		Persona:     "p",
+		Description: "d",
+		Model:       "unknown-model",
+		Models:      []string{"gpt-5.1-codex", "claude-sonnet-4.5", "gemini-2.5-pro"},
+	}
+	report := &Report{}
+	out, err := selector.Run(WithReport(context.Background(), report), agent, "task", "/tmp", agent.Model)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "copilot-out" {
+		t.Fatalf("expected copilot output, got %q", out)
+	}
+	if !codex.called || codex.model != "gpt-5.1-codex" {
+		t.Fatalf("expected codex to be tried with gpt-5.1-codex, got called=%v model=%q", codex.called, codex.model)
+	}
+	if copilot.model != "claude-sonnet-4.5" {
+		t.Fatalf("expected copilot to run claude-sonnet-4.5, got %q", copilot.model)
+	}
+	if gemini.called {
+		t.Fatal("expected gemini not to be called after copilot success")
+	}
+	if report.Runner != "copilot" || report.Model != "claude-sonnet-4.5" {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+}
+
+func TestSelector_ModelListUnsupported(t *testing.T) {
+	origFactories := runnerFactories
+	defer func() { runnerFactories = origFactories }()
+
+	codex := &fakeRunner{name: "codex", output: "codex-out"}
+	runnerFactories = map[string]func(*zap.Logger, []string) AgentRunner{
+		"codex": func(_ *zap.Logger, _ []string) AgentRunner { return codex },
+	}
+
+	selector, err := NewSelector(zap.NewNop(), Config{Runners: []RunnerConfig{{Name: "codex", Priority: 1, Models: []string{"gpt-5"}}}}, "")
+	if err != nil {
+		t.Fatalf("NewSelector error: %v", err)
+	}
+
+	agent := agents.Agent{Name: "a", Persona: "p", Description: "d", Models: []string{"claude", "gemini"}}
+	if _, err := selector.Run(context.Background(), agent, "task", "/tmp", ""); err == nil {
+		t.Fatal("expected error when no runner supports any model")
+	}
+	if codex.called {
+		t.Fatal("expected codex not to be called")
+	}
+}
+
+type deadlineRunner struct {
+	called      bool
+	hadDeadline bool
+	runErr      error
+	output      string
+}
+
+func (d *deadlineRunner) Run(ctx context.Context, agent agents.Agent, task string, workdir string, model string) (string, error) {
+	d.called = true
+	_, d.hadDeadline = ctx.Deadline()
+	return d.output, d.runErr
+}
+
+func TestSelector_TimeoutFallsBack(t *testing.T) {
+	origFactories := runnerFactories
+	defer func() { runnerFactories = origFactories }()
+
+	codex := &deadlineRunner{runErr: &ErrTimeout{RunnerName: "codex", Partial: "half"}}
+	copilot := &deadlineRunner{output: "copilot-out"}
+
+	runnerFactories = map[string]func(*zap.Logger, []string) AgentRunner{
+		"codex":   func(_ *zap.Logger, _ []string) AgentRunner { return codex },
+		"copilot": func(_ *zap.Logger, _ []string) AgentRunner { return copilot },
+	}
+
+	cfg := Config{
+		Runners: []RunnerConfig{
+			{Name: "codex", Priority: 1},
+			{Name: "copilot", Priority: 2},
+		},
+		DefaultTimeout: time.Minute,
+	}
+
+	selector, err := NewSelector(zap.NewNop(), cfg, "")
+	if err != nil {
+		t.Fatalf("NewSelector error: %v", err)
+	}
+
+	out, err := selector.Run(context.Background(), agents.Agent{Name: "a", Persona: "p", Description: "d"}, "task", "/tmp", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "copilot-out" {
+		t.Fatalf("expected copilot output, got %q", out)
+	}
+	if !codex.hadDeadline || !copilot.hadDeadline {
+		t.Fatal("expected default timeout to set a deadline on each attempt")
+	}
+
+	copilot.runErr = &ErrTimeout{RunnerName: "copilot"}
+	_, err = selector.Run(context.Background(), agents.Agent{Name: "a", Persona: "p", Description: "d"}, "task", "/tmp", "")
+	var timeoutErr *ErrTimeout
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected timeout error when all runners time out, got %v", err)
+	}
+}
+
+func TestSelector_NoTimeoutWithoutConfig(t *testing.T) {
+	origFactories := runnerFactories
+	defer func() { runnerFactories = origFactories }()
+
+	codex := &deadlineRunner{output: "ok"}
+	runnerFactories = map[string]func(*zap.Logger, []string) AgentRunner{
+		"codex": func(_ *zap.Logger, _ []string) AgentRunner { return codex },
+	}
+
+	selector, err := NewSelector(zap.NewNop(), Config{Runners: []RunnerConfig{{Name: "codex", Priority: 1}}}, "")
+	if err != nil {
+		t.Fatalf("NewSelector error: %v", err)
+	}
+	if _, err := selector.Run(context.Background(), agents.Agent{Name: "a", Persona: "p", Description: "d"}, "task", "/tmp", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if codex.hadDeadline {
+		t.Fatal("expected no deadline without a configured timeout")
+	}
+
+	agent := agents.Agent{Name: "a", Persona: "p", Description: "d", Timeout: time.Second}
+	if _, err := selector.Run(context.Background(), agent, "task", "/tmp", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !codex.hadDeadline {
+		t.Fatal("expected agent timeout to set a deadline")
+	}
+}
+
+func TestSelector_SkipsRunnersThatCannotHonorAgent(t *testing.T) {
+	origFactories := runnerFactories
+	defer func() { runnerFactories = origFactories }()
+
+	copilot := &fakeRunner{
+		name:   "copilot",
+		runErr: &ErrUnsupported{RunnerName: "copilot", Reason: "permissions \"workspace-write\" not supported"},
+	}
+	codex := &fakeRunner{name: "codex", output: "codex-out"}
+
+	runnerFactories = map[string]func(*zap.Logger, []string) AgentRunner{
+		"codex":   func(_ *zap.Logger, _ []string) AgentRunner { return codex },
+		"copilot": func(_ *zap.Logger, _ []string) AgentRunner { return copilot },
+	}
+
+	cfg := Config{
+		Runners: []RunnerConfig{
+			{Name: "codex", Priority: 2},
+			{Name: "copilot", Priority: 1},
+		},
+	}
+
+	selector, err := NewSelector(zap.NewNop(), cfg, "copilot")
+	if err != nil {
+		t.Fatalf("NewSelector error: %v", err)
+	}
+
+	agent := agents.Agent{Name: "a", Persona: "p", Description: "d", Permissions: agents.PermissionsWorkspaceWrite}
+	out, err := selector.Run(context.Background(), agent, "task", "/tmp", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "codex-out" || !copilot.called {
+		t.Fatalf("expected copilot skipped and codex used, got %q", out)
+	}
+
+	agent.Runners = []string{"copilot"}
+	if _, err := selector.Run(context.Background(), agent, "task", "/tmp", ""); !IsUnsupportedError(err) {
+		t.Fatalf("expected unsupported error when no runner can serve, got %v", err)
+	}
+}
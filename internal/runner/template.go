@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"subagents-mcp/internal/agents"
+)
+
+// now is overridable in tests.
+var now = time.Now
+
+// gitOutput runs a git query in dir; overridable in tests.
+var gitOutput = func(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// PromptVariables collects the variables available to persona templates:
+// caller supplied values overlaid with WorkingDirectory, Date, GitBranch and
+// RepoName, so callers cannot override the built-ins. Git details are empty
+// when workdir is not a repository.
+func PromptVariables(ctx context.Context, workdir string, extra map[string]any) map[string]any {
+	vars := make(map[string]any, len(extra)+4)
+	for k, v := range extra {
+		vars[k] = v
+	}
+	vars["WorkingDirectory"] = workdir
+	vars["Date"] = now().Format("2006-01-02")
+	vars["GitBranch"] = ""
+	vars["RepoName"] = ""
+	if branch, err := gitOutput(ctx, workdir, "rev-parse", "--abbrev-ref", "HEAD"); err == nil {
+		vars["GitBranch"] = branch
+	}
+	if top, err := gitOutput(ctx, workdir, "rev-parse", "--show-toplevel"); err == nil && top != "" {
+		vars["RepoName"] = filepath.Base(top)
+	}
+	return vars
+}
+
+type promptVariablesKey struct{}
+
+// WithPromptVariables returns a context carrying vars from PromptVariables,
+// so every attempt of a delegation reuses them instead of querying git again.
+func WithPromptVariables(ctx context.Context, vars map[string]any) context.Context {
+	return context.WithValue(ctx, promptVariablesKey{}, vars)
+}
+
+// promptVariables is PromptVariables, reusing the variables carried by ctx
+// when there are any.
+func promptVariables(ctx context.Context, workdir string, extra map[string]any) map[string]any {
+	base, ok := ctx.Value(promptVariablesKey{}).(map[string]any)
+	if !ok {
+		return PromptVariables(ctx, workdir, extra)
+	}
+	vars := make(map[string]any, len(base)+len(extra))
+	for k, v := range base {
+		vars[k] = v
+	}
+	for k, v := range extra {
+		vars[k] = v
+	}
+	return vars
+}
+
+// RenderPersona executes the persona of an agent with PersonaTemplate set as a
+// text/template against vars and returns a copy of the agent with the
+// rendered persona. Every variable the template references must be present in
+// vars. Other personas are returned unchanged.
+func RenderPersona(agent agents.Agent, vars map[string]any) (agents.Agent, error) {
+	if !agent.PersonaTemplate {
+		return agent, nil
+	}
+	tmpl, err := template.New(agent.Name).Option("missingkey=error").Parse(agent.Persona)
+	if err != nil {
+		return agents.Agent{}, fmt.Errorf("parse persona template for agent %q: %w", agent.Name, err)
+	}
+
+	var missing []string
+	for _, name := range agents.TemplateFields(tmpl) {
+		if _, ok := vars[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return agents.Agent{}, fmt.Errorf("agent %q requires missing variables: %s", agent.Name, strings.Join(missing, ", "))
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return agents.Agent{}, fmt.Errorf("render persona for agent %q: %w", agent.Name, err)
+	}
+	agent.Persona = strings.TrimSpace(buf.String())
+	return agent, nil
+}
@@ -0,0 +1,81 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"subagents-mcp/internal/agents"
+)
+
+func TestPromptVariables(t *testing.T) {
+	origNow, origGit := now, gitOutput
+	defer func() { now, gitOutput = origNow, origGit }()
+
+	now = func() time.Time { return time.Date(2025, 12, 1, 10, 0, 0, 0, time.UTC) }
+	gitOutput = func(ctx context.Context, dir string, args ...string) (string, error) {
+		if args[len(args)-1] == "HEAD" {
+			return "main", nil
+		}
+		return "/src/subagents-mcp", nil
+	}
+
+	vars := PromptVariables(context.Background(), "/src/subagents-mcp", map[string]any{"Ticket": "ABC-1", "WorkingDirectory": "/elsewhere"})
+	expected := map[string]any{
+		"WorkingDirectory": "/src/subagents-mcp",
+		"Date":             "2025-12-01",
+		"GitBranch":        "main",
+		"RepoName":         "subagents-mcp",
+		"Ticket":           "ABC-1",
+	}
+	for k, v := range expected {
+		if vars[k] != v {
+			t.Fatalf("expected %s=%v, got %v", k, v, vars[k])
+		}
+	}
+
+	gitOutput = func(ctx context.Context, dir string, args ...string) (string, error) {
+		return "", errors.New("not a git repository")
+	}
+	vars = PromptVariables(context.Background(), "/tmp", nil)
+	if vars["GitBranch"] != "" || vars["RepoName"] != "" {
+		t.Fatalf("expected empty git variables, got %v", vars)
+	}
+}
+
+func TestRenderPersona(t *testing.T) {
+	agent := agents.Agent{Name: "a", Persona: "Work in {{.WorkingDirectory}} on {{.Date}}.{{if .Ticket}} Ticket {{.Ticket}}.{{end}}", Description: "d", PersonaTemplate: true}
+
+	t.Run("renders variables", func(t *testing.T) {
+		rendered, err := RenderPersona(agent, map[string]any{"WorkingDirectory": "/repo", "Date": "2025-12-01", "Ticket": "ABC-1"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if rendered.Persona != "Work in /repo on 2025-12-01. Ticket ABC-1." {
+			t.Fatalf("unexpected persona: %q", rendered.Persona)
+		}
+	})
+
+	t.Run("reports missing variables", func(t *testing.T) {
+		_, err := RenderPersona(agent, map[string]any{"Date": "2025-12-01"})
+		if err == nil {
+			t.Fatal("expected missing variable error")
+		}
+		if !strings.Contains(err.Error(), "Ticket, WorkingDirectory") {
+			t.Fatalf("expected missing variables to be named, got %v", err)
+		}
+	})
+
+	t.Run("leaves personas without persona_template untouched", func(t *testing.T) {
+		plain := agents.Agent{Name: "a", Persona: "Keep {{ literal }} braces", Description: "d"}
+		rendered, err := RenderPersona(plain, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if rendered.Persona != "Keep {{ literal }} braces" {
+			t.Fatalf("unexpected persona: %q", rendered.Persona)
+		}
+	})
+}
@@ -0,0 +1,353 @@
+// Package schema validates decoded JSON/YAML values against the subset of
+// JSON Schema used by agent definitions: type, properties, required,
+// additionalProperties, items, enum, const, minLength/maxLength,
+// minimum/maximum, minItems/maxItems and pattern, plus the title,
+// description, default, examples and $comment annotations.
+package schema
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Error lists every problem found while validating a value.
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return strings.Join(e.Problems, "; ")
+}
+
+var knownTypes = map[string]struct{}{
+	"object": {}, "array": {}, "string": {}, "number": {},
+	"integer": {}, "boolean": {}, "null": {},
+}
+
+// keywords lists the schema keywords Check accepts; Validate enforces all of
+// them except the annotations.
+var keywords = map[string]struct{}{
+	"type": {}, "properties": {}, "required": {}, "additionalProperties": {},
+	"items": {}, "enum": {}, "const": {}, "minLength": {}, "maxLength": {},
+	"minimum": {}, "maximum": {}, "minItems": {}, "maxItems": {}, "pattern": {},
+	"title": {}, "description": {}, "default": {}, "examples": {}, "$comment": {},
+}
+
+// Check reports structural problems in a schema, such as unknown types,
+// unsupported or malformed keywords, so broken definitions are caught at
+// load time instead of being silently ignored by Validate.
+func Check(s map[string]any) error {
+	var problems []string
+	checkSchema("$", s, &problems)
+	if len(problems) > 0 {
+		return &Error{Problems: problems}
+	}
+	return nil
+}
+
+// Validate checks value against s and returns an *Error describing every
+// mismatch, or nil when the value conforms.
+func Validate(s map[string]any, value any) error {
+	var problems []string
+	validate("$", s, value, &problems)
+	if len(problems) > 0 {
+		return &Error{Problems: problems}
+	}
+	return nil
+}
+
+func checkSchema(path string, s map[string]any, problems *[]string) {
+	for _, key := range sortedKeys(s) {
+		if _, ok := keywords[key]; !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: unsupported keyword %q", path, key))
+		}
+	}
+	if raw, ok := s["type"]; ok {
+		if _, ok := stringList(raw); !ok {
+			if _, ok := raw.(string); !ok {
+				*problems = append(*problems, fmt.Sprintf("%s: type must be a string or a list of strings", path))
+			}
+		}
+	}
+	for _, t := range typeList(s["type"]) {
+		if _, ok := knownTypes[t]; !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: unknown type %q", path, t))
+		}
+	}
+	if raw, ok := s["properties"]; ok {
+		props, ok := asObject(raw)
+		if !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: properties must be an object", path))
+		}
+		for _, name := range sortedKeys(props) {
+			sub, ok := asObject(props[name])
+			if !ok {
+				*problems = append(*problems, fmt.Sprintf("%s.%s: schema must be an object", path, name))
+				continue
+			}
+			checkSchema(path+"."+name, sub, problems)
+		}
+	}
+	if raw, ok := s["items"]; ok {
+		sub, ok := asObject(raw)
+		if !ok {
+			*problems = append(*problems, fmt.Sprintf("%s[]: schema must be an object", path))
+		} else {
+			checkSchema(path+"[]", sub, problems)
+		}
+	}
+	if raw, ok := s["additionalProperties"]; ok {
+		switch extra := raw.(type) {
+		case bool:
+		case map[string]any:
+			checkSchema(path+".*", extra, problems)
+		default:
+			*problems = append(*problems, fmt.Sprintf("%s: additionalProperties must be a boolean or a schema", path))
+		}
+	}
+	if raw, ok := s["enum"]; ok {
+		if _, ok := raw.([]any); !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: enum must be a list", path))
+		}
+	}
+	for _, key := range []string{"minLength", "maxLength", "minimum", "maximum", "minItems", "maxItems"} {
+		if raw, ok := s[key]; ok {
+			if _, ok := number(raw); !ok {
+				*problems = append(*problems, fmt.Sprintf("%s: %s must be a number", path, key))
+			}
+		}
+	}
+	if raw, ok := s["required"]; ok {
+		if _, ok := stringList(raw); !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: required must be a list of strings", path))
+		}
+	}
+	if raw, ok := s["pattern"]; ok {
+		pattern, ok := raw.(string)
+		if !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: pattern must be a string", path))
+		} else if _, err := regexp.Compile(pattern); err != nil {
+			*problems = append(*problems, fmt.Sprintf("%s: invalid pattern: %v", path, err))
+		}
+	}
+}
+
+func validate(path string, s map[string]any, value any, problems *[]string) {
+	if types := typeList(s["type"]); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if hasType(value, t) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			*problems = append(*problems, fmt.Sprintf("%s: expected %s, got %s", path, strings.Join(types, " or "), typeName(value)))
+			return
+		}
+	}
+
+	if raw, ok := s["enum"]; ok {
+		if options, ok := raw.([]any); ok {
+			found := false
+			for _, option := range options {
+				if equal(option, value) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				*problems = append(*problems, fmt.Sprintf("%s: must be one of %v", path, options))
+			}
+		}
+	}
+	if raw, ok := s["const"]; ok && !equal(raw, value) {
+		*problems = append(*problems, fmt.Sprintf("%s: must equal %v", path, raw))
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		validateObject(path, s, v, problems)
+	case []any:
+		if lo, ok := number(s["minItems"]); ok && float64(len(v)) < lo {
+			*problems = append(*problems, fmt.Sprintf("%s: must have at least %v items", path, lo))
+		}
+		if hi, ok := number(s["maxItems"]); ok && float64(len(v)) > hi {
+			*problems = append(*problems, fmt.Sprintf("%s: must have at most %v items", path, hi))
+		}
+		if items, ok := asObject(s["items"]); ok {
+			for i, item := range v {
+				validate(fmt.Sprintf("%s[%d]", path, i), items, item, problems)
+			}
+		}
+	case string:
+		length := float64(len([]rune(v)))
+		if lo, ok := number(s["minLength"]); ok && length < lo {
+			*problems = append(*problems, fmt.Sprintf("%s: must be at least %v characters", path, lo))
+		}
+		if hi, ok := number(s["maxLength"]); ok && length > hi {
+			*problems = append(*problems, fmt.Sprintf("%s: must be at most %v characters", path, hi))
+		}
+		if pattern, ok := s["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(v) {
+				*problems = append(*problems, fmt.Sprintf("%s: must match pattern %q", path, pattern))
+			}
+		}
+	default:
+		if n, ok := number(value); ok {
+			if lo, ok := number(s["minimum"]); ok && n < lo {
+				*problems = append(*problems, fmt.Sprintf("%s: must be >= %v", path, lo))
+			}
+			if hi, ok := number(s["maximum"]); ok && n > hi {
+				*problems = append(*problems, fmt.Sprintf("%s: must be <= %v", path, hi))
+			}
+		}
+	}
+}
+
+func validateObject(path string, s map[string]any, obj map[string]any, problems *[]string) {
+	required, _ := stringList(s["required"])
+	for _, name := range required {
+		if _, ok := obj[name]; !ok {
+			*problems = append(*problems, fmt.Sprintf("%s.%s: is required", path, name))
+		}
+	}
+
+	props, _ := asObject(s["properties"])
+	for _, name := range sortedKeys(obj) {
+		if sub, ok := asObject(props[name]); ok {
+			validate(path+"."+name, sub, obj[name], problems)
+			continue
+		}
+		switch extra := s["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				*problems = append(*problems, fmt.Sprintf("%s.%s: is not allowed", path, name))
+			}
+		case map[string]any:
+			validate(path+"."+name, extra, obj[name], problems)
+		}
+	}
+}
+
+func typeList(raw any) []string {
+	switch t := raw.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		out := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	case []string:
+		return t
+	}
+	return nil
+}
+
+func hasType(value any, t string) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	case "number":
+		_, ok := number(value)
+		return ok
+	case "integer":
+		n, ok := number(value)
+		return ok && n == math.Trunc(n)
+	}
+	return false
+}
+
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	if _, ok := number(value); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func number(value any) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func equal(a, b any) bool {
+	if na, ok := number(a); ok {
+		nb, ok := number(b)
+		return ok && na == nb
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b) && typeName(a) == typeName(b)
+}
+
+func asObject(raw any) (map[string]any, bool) {
+	obj, ok := raw.(map[string]any)
+	return obj, ok
+}
+
+func stringList(raw any) ([]string, bool) {
+	switch list := raw.(type) {
+	case []string:
+		return list, true
+	case []any:
+		out := make([]string, 0, len(list))
+		for _, item := range list {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			out = append(out, s)
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
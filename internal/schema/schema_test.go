@@ -0,0 +1,91 @@
+package schema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func decode(t *testing.T, raw string) map[string]any {
+	t.Helper()
+	var out map[string]any
+	if err := json.Unmarshal([]byte(raw), &out); err != nil {
+		t.Fatalf("unmarshal %s: %v", raw, err)
+	}
+	return out
+}
+
+func TestValidate(t *testing.T) {
+	review := decode(t, `{
+		"type": "object",
+		"properties": {
+			"base_ref": {"type": "string", "minLength": 1},
+			"paths": {"type": "array", "items": {"type": "string"}, "minItems": 1},
+			"depth": {"type": "integer", "minimum": 1, "maximum": 3},
+			"mode": {"enum": ["quick", "thorough"]}
+		},
+		"required": ["base_ref", "paths"],
+		"additionalProperties": false
+	}`)
+
+	t.Run("accepts conforming value", func(t *testing.T) {
+		value := decode(t, `{"base_ref": "main", "paths": ["a.go"], "depth": 2, "mode": "quick"}`)
+		if err := Validate(review, value); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("reports every problem", func(t *testing.T) {
+		value := decode(t, `{"paths": [1], "depth": 2.5, "mode": "slow", "extra": true}`)
+		err := Validate(review, value)
+		if err == nil {
+			t.Fatal("expected validation error")
+		}
+		for _, want := range []string{
+			"$.base_ref: is required",
+			"$.paths[0]: expected string, got number",
+			"$.depth: expected integer",
+			"$.mode: must be one of",
+			"$.extra: is not allowed",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Fatalf("expected %q in %v", want, err)
+			}
+		}
+	})
+
+	t.Run("accepts yaml decoded integers", func(t *testing.T) {
+		s := map[string]any{"type": "object", "properties": map[string]any{"n": map[string]any{"type": "integer", "maximum": 5}}}
+		if err := Validate(s, map[string]any{"n": 3}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestCheck(t *testing.T) {
+	if err := Check(decode(t, `{"type": "object", "properties": {"a": {"type": "string", "pattern": "^x"}}}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := Check(decode(t, `{"type": "object", "properties": {"a": {"type": "text"}, "b": {"pattern": "("}}, "required": [1]}`))
+	if err == nil {
+		t.Fatal("expected schema error")
+	}
+	for _, want := range []string{`$.a: unknown type "text"`, "$.b: invalid pattern", "required must be a list of strings"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in %v", want, err)
+		}
+	}
+
+	err = Check(decode(t, `{"oneOf": [{"type": "string"}], "format": "date", "exclusiveMinimum": 3, "items": [{"type": "string"}], "minimum": "3"}`))
+	if err == nil {
+		t.Fatal("expected unsupported keywords to be rejected")
+	}
+	for _, want := range []string{`unsupported keyword "oneOf"`, `unsupported keyword "format"`, `unsupported keyword "exclusiveMinimum"`, "$[]: schema must be an object", "minimum must be a number"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in %v", want, err)
+		}
+	}
+	if err := Check(decode(t, `{"title": "T", "description": "d", "type": ["string", "null"], "default": null, "additionalProperties": {"type": "string"}}`)); err != nil {
+		t.Fatalf("unexpected error for annotations: %v", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 var (
@@ -55,3 +56,20 @@ func Dir(p string) (string, error) {
 
 	return resolved, nil
 }
+
+// Within resolves symlinks in p and ensures the result stays inside root.
+// root is expected to be an already-resolved directory, as returned by Dir.
+func Within(root, p string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return "", fmt.Errorf("resolve path: %w", err)
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil {
+		return "", ErrSymlinkEscape
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", ErrSymlinkEscape
+	}
+	return resolved, nil
+}
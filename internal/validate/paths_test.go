@@ -1,6 +1,7 @@
 package validate
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -61,3 +62,35 @@ func TestDirValidation(t *testing.T) {
 		}
 	})
 }
+
+func TestWithinValidation(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("eval symlinks: %v", err)
+	}
+	outside := t.TempDir()
+
+	t.Run("accepts nested path", func(t *testing.T) {
+		nested := filepath.Join(root, "nested")
+		if err := os.Mkdir(nested, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		resolved, err := Within(root, nested)
+		if err != nil {
+			t.Fatalf("expected success, got %v", err)
+		}
+		if resolved != nested {
+			t.Fatalf("expected %s, got %s", nested, resolved)
+		}
+	})
+
+	t.Run("rejects symlink escaping root", func(t *testing.T) {
+		link := filepath.Join(root, "escape")
+		if err := os.Symlink(outside, link); err != nil {
+			t.Fatalf("symlink: %v", err)
+		}
+		if _, err := Within(root, link); !errors.Is(err, ErrSymlinkEscape) {
+			t.Fatalf("expected ErrSymlinkEscape, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package validate
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// UnderRoots reports whether p, or one of its parent directories, matches
+// one of the root globs (filepath.Match syntax). p is expected to be resolved,
+// as returned by Dir; roots are matched both as written and with the literal
+// directories before their first wildcard symlink-resolved.
+func UnderRoots(p string, roots []string) bool {
+	for _, root := range roots {
+		pattern := filepath.Clean(root)
+		for _, candidate := range []string{pattern, resolvePrefix(pattern)} {
+			for dir := p; ; dir = filepath.Dir(dir) {
+				if ok, _ := filepath.Match(candidate, dir); ok {
+					return true
+				}
+				if parent := filepath.Dir(dir); parent == dir {
+					break
+				}
+			}
+		}
+	}
+	return false
+}
+
+// resolvePrefix resolves symlinks in the leading literal directories of a
+// root glob, e.g. /home/*/src becomes /usr/home/*/src when /home links
+// there. The pattern is returned unchanged when nothing resolves.
+func resolvePrefix(pattern string) string {
+	prefix, rest := pattern, ""
+	for strings.ContainsAny(prefix, `*?[\`) {
+		rest = filepath.Join(filepath.Base(prefix), rest)
+		prefix = filepath.Dir(prefix)
+	}
+	resolved, err := filepath.EvalSymlinks(prefix)
+	if err != nil {
+		return pattern
+	}
+	return filepath.Join(resolved, rest)
+}
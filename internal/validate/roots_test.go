@@ -0,0 +1,49 @@
+package validate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnderRoots(t *testing.T) {
+	cases := []struct {
+		path  string
+		roots []string
+		want  bool
+	}{
+		{"/srv/repos/app", []string{"/srv/repos"}, true},
+		{"/srv/repos/app/sub", []string{"/srv/repos/*"}, true},
+		{"/srv/repos", []string{"/srv/repos/*"}, false},
+		{"/srv/other/app", []string{"/srv/repos", "/home/*/src"}, false},
+		{"/home/dev/src/app", []string{"/srv/repos", "/home/*/src"}, true},
+		{"/srv/repos-evil", []string{"/srv/repos"}, false},
+		{"/srv/repos/app", nil, false},
+	}
+	for _, tc := range cases {
+		if got := UnderRoots(tc.path, tc.roots); got != tc.want {
+			t.Fatalf("UnderRoots(%q, %q) = %v, want %v", tc.path, tc.roots, got, tc.want)
+		}
+	}
+}
+
+func TestUnderRootsResolvesGlobPrefix(t *testing.T) {
+	real := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(real, "dev", "src", "app"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(t.TempDir(), "home")
+	if err := os.Symlink(real, link); err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Join(real, "dev", "src", "app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !UnderRoots(resolved, []string{filepath.Join(link, "*", "src")}) {
+		t.Fatalf("expected %s to match a glob below the symlink %s", resolved, link)
+	}
+	if UnderRoots(resolved, []string{filepath.Join(link, "*", "docs")}) {
+		t.Fatal("unexpected match")
+	}
+}